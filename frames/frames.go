@@ -0,0 +1,201 @@
+package frames
+
+import (
+	"context"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"net/url"
+	"strings"
+	"time"
+
+	"github.com/vocdoni/farcaster-go/hub"
+	hubproto "github.com/vocdoni/farcaster-go/hub/proto"
+	"google.golang.org/protobuf/proto"
+)
+
+const (
+	// DefaultMaxAge is the default maximum age of a valid frame action.
+	DefaultMaxAge = 10 * time.Minute
+	// maxClockSkew is the maximum time that the timestamp of a frame action
+	// can be in the future, to tolerate small clock differences.
+	maxClockSkew = time.Minute
+	// maxButtonIndex is the maximum index of the buttons of a frame.
+	maxButtonIndex = 4
+)
+
+var (
+	// ErrInvalidPayload is returned when the frame payload can not be decoded
+	// or it does not contain a valid frame action message.
+	ErrInvalidPayload = fmt.Errorf("invalid frame payload")
+	// ErrInvalidURL is returned when the URL of the frame action does not
+	// match the expected frame URL.
+	ErrInvalidURL = fmt.Errorf("invalid frame url")
+	// ErrExpiredAction is returned when the frame action is too old or its
+	// timestamp is in the future.
+	ErrExpiredAction = fmt.Errorf("expired frame action")
+)
+
+// ValidatePayload function decodes the given body of a frame POST request and
+// validates it with Validate.
+func ValidatePayload(ctx context.Context, body []byte, opts *ValidateOptions) (*Action, error) {
+	payload := &Payload{}
+	if err := json.Unmarshal(body, payload); err != nil {
+		return nil, fmt.Errorf("%w: %w", ErrInvalidPayload, err)
+	}
+	return Validate(ctx, payload, opts)
+}
+
+// Validate function validates the given frame payload. It decodes the signed
+// message of the trusted data, checks that it is a frame action, verifies its
+// hash and signature (and the network and the signer if they are included in
+// the options), and checks its URL and that it is not older than the maximum
+// age. If a hub is included in the options, the message is also validated by
+// the hub. The untrusted data is ignored. It returns the verified action or an
+// error that matches ErrInvalidPayload, ErrInvalidURL, ErrExpiredAction or the
+// hub verification errors.
+func Validate(ctx context.Context, payload *Payload, opts *ValidateOptions) (*Action, error) {
+	if opts == nil {
+		opts = &ValidateOptions{}
+	}
+	if payload == nil || payload.TrustedData == nil || payload.TrustedData.MessageBytes == "" {
+		return nil, fmt.Errorf("%w: no trusted data", ErrInvalidPayload)
+	}
+	// decode the signed message
+	msgBytes, err := hex.DecodeString(strings.TrimPrefix(payload.TrustedData.MessageBytes, "0x"))
+	if err != nil {
+		return nil, fmt.Errorf("%w: %w", ErrInvalidPayload, err)
+	}
+	msg := &hubproto.Message{}
+	if err := proto.Unmarshal(msgBytes, msg); err != nil {
+		return nil, fmt.Errorf("%w: %w", ErrInvalidPayload, err)
+	}
+	// verify the message hash, the signature, the network and the signer
+	if err := hub.VerifyMessage(msg, opts.Network, opts.IsActiveSigner); err != nil {
+		return nil, err
+	}
+	body := msg.Data.GetFrameActionBody()
+	if msg.Data.Type != hubproto.MessageType_MESSAGE_TYPE_FRAME_ACTION || body == nil {
+		return nil, fmt.Errorf("%w: not a frame action", ErrInvalidPayload)
+	}
+	if body.ButtonIndex < 1 || body.ButtonIndex > maxButtonIndex {
+		return nil, fmt.Errorf("%w: invalid button index %d", ErrInvalidPayload, body.ButtonIndex)
+	}
+	// check the url and the timestamp
+	if opts.URL != "" && !matchURL(opts.URL, string(body.Url)) {
+		return nil, fmt.Errorf("%w: %s", ErrInvalidURL, body.Url)
+	}
+	maxAge := opts.MaxAge
+	if maxAge == 0 {
+		maxAge = DefaultMaxAge
+	}
+	timestamp := time.Unix(int64(hub.UnixTimestamp(msg.Data.Timestamp)), 0)
+	if age := time.Since(timestamp); age > maxAge || age < -maxClockSkew {
+		return nil, fmt.Errorf("%w: %s", ErrExpiredAction, timestamp)
+	}
+	// cross-check the message with the hub if it is provided
+	if opts.Hub != nil {
+		if err := opts.Hub.ValidateMessage(ctx, msg); err != nil {
+			return nil, err
+		}
+	}
+	action := &Action{
+		FID:         msg.Data.Fid,
+		URL:         string(body.Url),
+		ButtonIndex: body.ButtonIndex,
+		InputText:   string(body.InputText),
+		State:       string(body.State),
+		Timestamp:   uint64(timestamp.Unix()),
+		Hash:        "0x" + hex.EncodeToString(msg.Hash),
+		Signer:      msg.Signer,
+		Message:     msg,
+	}
+	if body.CastId != nil {
+		action.CastID = &hub.CastID{
+			FID:  body.CastId.Fid,
+			Hash: "0x" + hex.EncodeToString(body.CastId.Hash),
+		}
+	}
+	return action, nil
+}
+
+// NewPayload function creates the payload of a frame action of the user with
+// the given fid, for the given network, signed with the given signer. It
+// builds the frame action message with hub.BuildMessage and includes its data
+// as untrusted data too, as the Farcaster clients do.
+func NewPayload(fid uint64, network hubproto.FarcasterNetwork, signer hub.MessageSigner,
+	params *ActionParams,
+) (*Payload, error) {
+	if params == nil || params.URL == "" {
+		return nil, fmt.Errorf("no frame url provided")
+	}
+	if params.ButtonIndex < 1 || params.ButtonIndex > maxButtonIndex {
+		return nil, fmt.Errorf("invalid button index: %d", params.ButtonIndex)
+	}
+	body := &hubproto.FrameActionBody{
+		Url:         []byte(params.URL),
+		ButtonIndex: params.ButtonIndex,
+		InputText:   []byte(params.InputText),
+		State:       []byte(params.State),
+	}
+	var castID *castIDData
+	if params.CastID != nil {
+		hash, err := hex.DecodeString(strings.TrimPrefix(params.CastID.Hash, "0x"))
+		if err != nil {
+			return nil, fmt.Errorf("error decoding cast hash: %w", err)
+		}
+		body.CastId = &hubproto.CastId{Fid: params.CastID.FID, Hash: hash}
+		castID = &castIDData{FID: params.CastID.FID, Hash: params.CastID.Hash}
+	}
+	now := time.Now()
+	msg, msgBytes, err := hub.BuildMessage(&hubproto.MessageData{
+		Type:      hubproto.MessageType_MESSAGE_TYPE_FRAME_ACTION,
+		Fid:       fid,
+		Timestamp: hub.FarcasterTimestamp(now),
+		Network:   network,
+		Body:      &hubproto.MessageData_FrameActionBody{FrameActionBody: body},
+	}, signer)
+	if err != nil {
+		return nil, fmt.Errorf("error building frame action: %w", err)
+	}
+	return &Payload{
+		UntrustedData: &UntrustedData{
+			FID:         fid,
+			URL:         params.URL,
+			MessageHash: "0x" + hex.EncodeToString(msg.Hash),
+			Timestamp:   uint64(now.UnixMilli()),
+			Network:     int32(network),
+			ButtonIndex: params.ButtonIndex,
+			InputText:   params.InputText,
+			State:       params.State,
+			CastID:      castID,
+		},
+		TrustedData: &TrustedData{
+			MessageBytes: hex.EncodeToString(msgBytes),
+		},
+	}, nil
+}
+
+// matchURL function returns if the given action URL belongs to the given
+// frame URL: both must have the same scheme and host, and the action URL path
+// must be the frame URL path or one of its subpaths.
+func matchURL(frameURL, actionURL string) bool {
+	expected, err := url.Parse(frameURL)
+	if err != nil {
+		return false
+	}
+	actual, err := url.Parse(actionURL)
+	if err != nil {
+		return false
+	}
+	if !strings.EqualFold(expected.Scheme, actual.Scheme) || !strings.EqualFold(expected.Host, actual.Host) {
+		return false
+	}
+	// the path must match whole segments, so "/poll" does not match
+	// "/pollution"
+	prefix := expected.Path
+	if !strings.HasSuffix(prefix, "/") {
+		prefix += "/"
+	}
+	return actual.Path == expected.Path || strings.HasPrefix(actual.Path, prefix)
+}
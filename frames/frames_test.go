@@ -0,0 +1,117 @@
+package frames
+
+import (
+	"context"
+	"crypto/ed25519"
+	"encoding/hex"
+	"encoding/json"
+	"testing"
+	"time"
+
+	qt "github.com/frankban/quicktest"
+	"github.com/vocdoni/farcaster-go/hub"
+	hubproto "github.com/vocdoni/farcaster-go/hub/proto"
+	"google.golang.org/protobuf/proto"
+)
+
+func TestValidate(t *testing.T) {
+	q := qt.New(t)
+	ctx := context.Background()
+
+	_, key, err := ed25519.GenerateKey(nil)
+	q.Assert(err, qt.IsNil)
+	signer := hub.NewEd25519Signer(key)
+	network := hubproto.FarcasterNetwork_FARCASTER_NETWORK_MAINNET
+	castID := &hub.CastID{FID: 3, Hash: "0x" + hex.EncodeToString(make([]byte, hub.MessageHashLength))}
+	payload, err := NewPayload(529726, network, signer, &ActionParams{
+		URL:         "https://frames.vocdoni.io/poll/1/vote",
+		ButtonIndex: 2,
+		InputText:   "yes",
+		State:       `{"step":1}`,
+		CastID:      castID,
+	})
+	q.Assert(err, qt.IsNil)
+	body, err := json.Marshal(payload)
+	q.Assert(err, qt.IsNil)
+
+	// valid payload, the trusted data is decoded
+	opts := &ValidateOptions{URL: "https://frames.vocdoni.io/poll", Network: network}
+	action, err := ValidatePayload(ctx, body, opts)
+	q.Assert(err, qt.IsNil)
+	q.Assert(action.FID, qt.Equals, uint64(529726))
+	q.Assert(action.ButtonIndex, qt.Equals, uint32(2))
+	q.Assert(action.InputText, qt.Equals, "yes")
+	q.Assert(action.State, qt.Equals, `{"step":1}`)
+	q.Assert(action.CastID, qt.DeepEquals, castID)
+	q.Assert(action.Hash, qt.Equals, payload.UntrustedData.MessageHash)
+
+	// the untrusted data is ignored
+	payload.UntrustedData.FID = 1
+	payload.UntrustedData.ButtonIndex = 1
+	action, err = Validate(ctx, payload, opts)
+	q.Assert(err, qt.IsNil)
+	q.Assert(action.FID, qt.Equals, uint64(529726))
+	q.Assert(action.ButtonIndex, qt.Equals, uint32(2))
+
+	// other frame url
+	_, err = Validate(ctx, payload, &ValidateOptions{URL: "https://evil.example/poll"})
+	q.Assert(err, qt.ErrorIs, ErrInvalidURL)
+	_, err = Validate(ctx, payload, &ValidateOptions{URL: "https://frames.vocdoni.io/pol"})
+	q.Assert(err, qt.ErrorIs, ErrInvalidURL)
+	// other network
+	_, err = Validate(ctx, payload, &ValidateOptions{Network: hubproto.FarcasterNetwork_FARCASTER_NETWORK_TESTNET})
+	q.Assert(err, qt.ErrorIs, hub.ErrNetworkMismatch)
+	// inactive signer
+	inactive := func(uint64, []byte) (bool, error) { return false, nil }
+	_, err = Validate(ctx, payload, &ValidateOptions{IsActiveSigner: inactive})
+	q.Assert(err, qt.ErrorIs, hub.ErrInactiveSigner)
+
+	// tampered message
+	msgBytes, err := hex.DecodeString(payload.TrustedData.MessageBytes)
+	q.Assert(err, qt.IsNil)
+	msg := &hubproto.Message{}
+	q.Assert(proto.Unmarshal(msgBytes, msg), qt.IsNil)
+	msg.Data.GetFrameActionBody().ButtonIndex = 1
+	msg.DataBytes = nil
+	tampered, err := proto.Marshal(msg)
+	q.Assert(err, qt.IsNil)
+	_, err = Validate(ctx, &Payload{TrustedData: &TrustedData{MessageBytes: hex.EncodeToString(tampered)}}, nil)
+	q.Assert(err, qt.ErrorIs, hub.ErrInvalidHash)
+
+	// expired action
+	data := proto.Clone(msg.Data).(*hubproto.MessageData)
+	data.Timestamp = hub.FarcasterTimestamp(time.Now().Add(-time.Hour))
+	_, expiredBytes, err := hub.BuildMessage(data, signer)
+	q.Assert(err, qt.IsNil)
+	_, err = Validate(ctx, &Payload{TrustedData: &TrustedData{MessageBytes: hex.EncodeToString(expiredBytes)}}, nil)
+	q.Assert(err, qt.ErrorIs, ErrExpiredAction)
+
+	// invalid payload
+	_, err = ValidatePayload(ctx, []byte(`{"trustedData":{"messageBytes":"zz"}}`), nil)
+	q.Assert(err, qt.ErrorIs, ErrInvalidPayload)
+}
+
+func TestMatchURL(t *testing.T) {
+	tests := []struct {
+		frameURL  string
+		actionURL string
+		expected  bool
+	}{
+		{"https://frames.vocdoni.io/poll", "https://frames.vocdoni.io/poll", true},
+		{"https://frames.vocdoni.io/poll", "https://frames.vocdoni.io/poll/1/vote", true},
+		{"https://frames.vocdoni.io/poll/", "https://frames.vocdoni.io/poll/1", true},
+		{"https://frames.vocdoni.io/poll", "HTTPS://Frames.Vocdoni.io/poll?vote=1", true},
+		{"https://frames.vocdoni.io", "https://frames.vocdoni.io/poll", true},
+		{"https://frames.vocdoni.io/", "https://frames.vocdoni.io/poll", true},
+		{"https://frames.vocdoni.io/poll", "https://frames.vocdoni.io/pollution", false},
+		{"https://frames.vocdoni.io/poll", "https://frames.vocdoni.io/poll-evil/1", false},
+		{"https://frames.vocdoni.io/poll", "https://frames.vocdoni.io/", false},
+		{"https://frames.vocdoni.io/poll", "http://frames.vocdoni.io/poll", false},
+		{"https://frames.vocdoni.io/poll", "https://evil.example/poll", false},
+		{"https://frames.vocdoni.io/poll", "https://frames.vocdoni.io.evil.example/poll", false},
+	}
+	for _, test := range tests {
+		qt.Check(t, matchURL(test.frameURL, test.actionURL), qt.Equals, test.expected,
+			qt.Commentf("frame: %s, action: %s", test.frameURL, test.actionURL))
+	}
+}
@@ -0,0 +1,86 @@
+package frames
+
+import (
+	"time"
+
+	"github.com/vocdoni/farcaster-go/hub"
+	hubproto "github.com/vocdoni/farcaster-go/hub/proto"
+)
+
+// Payload holds the body of the POST requests sent by the Farcaster clients
+// to the frame servers when a user interacts with a frame. Only the trusted
+// data can be trusted, the untrusted data is included by the clients for
+// convenience.
+type Payload struct {
+	UntrustedData *UntrustedData `json:"untrustedData"`
+	TrustedData   *TrustedData   `json:"trustedData"`
+}
+
+// UntrustedData holds the unverified data of a frame action, as it is
+// included by the Farcaster clients.
+type UntrustedData struct {
+	FID         uint64      `json:"fid"`
+	URL         string      `json:"url"`
+	MessageHash string      `json:"messageHash"`
+	Timestamp   uint64      `json:"timestamp"`
+	Network     int32       `json:"network"`
+	ButtonIndex uint32      `json:"buttonIndex"`
+	InputText   string      `json:"inputText,omitempty"`
+	State       string      `json:"state,omitempty"`
+	CastID      *castIDData `json:"castId,omitempty"`
+}
+
+// TrustedData holds the signed frame action message, hex encoded.
+type TrustedData struct {
+	MessageBytes string `json:"messageBytes"`
+}
+
+type castIDData struct {
+	FID  uint64 `json:"fid"`
+	Hash string `json:"hash"`
+}
+
+// Action holds the verified data of a frame action: the user that performed
+// it, the frame and the cast that contained it, the button pressed, the text
+// input and the state of the frame. The timestamp is a unix timestamp.
+type Action struct {
+	FID         uint64
+	URL         string
+	ButtonIndex uint32
+	InputText   string
+	State       string
+	CastID      *hub.CastID
+	Timestamp   uint64
+	Hash        string
+	Signer      []byte
+	Message     *hubproto.Message
+}
+
+// ActionParams holds the data of a frame action to be created with
+// NewPayload.
+type ActionParams struct {
+	URL         string
+	ButtonIndex uint32
+	InputText   string
+	State       string
+	CastID      *hub.CastID
+}
+
+// ValidateOptions holds the options to validate a frame action.
+type ValidateOptions struct {
+	// URL is the URL of the frame. If it is set, the action URL must be on
+	// the same host and start with the same path.
+	URL string
+	// MaxAge is the maximum age of the action. If it is 0, DefaultMaxAge is
+	// used.
+	MaxAge time.Duration
+	// Network is the farcaster network of the action. If it is
+	// FARCASTER_NETWORK_NONE, the network is not checked.
+	Network hubproto.FarcasterNetwork
+	// IsActiveSigner is an optional function to check that the signer of the
+	// action is an active key of the user.
+	IsActiveSigner hub.SignerValidator
+	// Hub is an optional hub API used to cross-check the action message with
+	// the hub, that also checks that the signer is an active key of the user.
+	Hub *hub.Hub
+}
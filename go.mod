@@ -11,6 +11,7 @@ require (
 	github.com/vocdoni/census3 v0.1.4-0.20240418065546-c3ac49eec357
 	github.com/zeebo/blake3 v0.2.3
 	go.vocdoni.io/dvote v1.10.2-0.20240313095944-f5790a5af0ed
+	golang.org/x/sync v0.7.0
 	google.golang.org/protobuf v1.34.1
 )
 
@@ -58,7 +59,6 @@ require (
 	golang.org/x/crypto v0.22.0 // indirect
 	golang.org/x/mod v0.17.0 // indirect
 	golang.org/x/net v0.24.0 // indirect
-	golang.org/x/sync v0.7.0 // indirect
 	golang.org/x/sys v0.19.0 // indirect
 	golang.org/x/tools v0.20.0 // indirect
 	rsc.io/tmplfunc v0.0.3 // indirect
@@ -0,0 +1,38 @@
+package hub
+
+import "context"
+
+// API interface defines the methods that a Farcaster backend must implement to
+// be used interchangeably by the consumers of this module. Both the Hub client
+// of this package and the Neynar client implement it, sharing the same data
+// model (APIMessage, Userdata and Channel).
+type API interface {
+	// SetFarcasterUser sets the farcaster user with the given fid and signer.
+	// The signer format depends on the backend.
+	SetFarcasterUser(fid uint64, signer string) error
+	// FID returns the fid of the farcaster user set in the API.
+	FID() uint64
+	// LastMentions returns the mentions of the configured user newer than the
+	// given timestamp, and the timestamp of the last one.
+	LastMentions(ctx context.Context, timestamp uint64) ([]*APIMessage, uint64, error)
+	// Cast returns the cast with the given author fid and hash.
+	Cast(ctx context.Context, fid uint64, hash string) (*APIMessage, error)
+	// Publish sends a new cast with the given content, mentions and embeds.
+	Publish(ctx context.Context, content string, mentionFIDs []uint64, embeds ...string) error
+	// Reply sends a reply to the given message with the given content,
+	// mentions and embeds.
+	Reply(ctx context.Context, targetMsg *APIMessage, content string, mentionFIDs []uint64, embeds ...string) error
+	// UserDataByFID returns the user data of the user with the given fid.
+	UserDataByFID(ctx context.Context, fid uint64) (*Userdata, error)
+	// UserFollowers returns the FIDs of the followers of the user with the
+	// given fid.
+	UserFollowers(ctx context.Context, fid uint64) ([]uint64, error)
+	// Channel returns the details of the channel with the given id.
+	Channel(ctx context.Context, channelID string) (*Channel, error)
+	// ChannelFIDs returns the FIDs of the users that follow the channel with
+	// the given id. If progress is not nil, the percentage of followers
+	// processed is sent through it.
+	ChannelFIDs(ctx context.Context, channelID string, progress chan int) ([]uint64, error)
+	// ChannelExists returns if the channel with the given id exists.
+	ChannelExists(ctx context.Context, channelID string) (bool, error)
+}
@@ -0,0 +1,35 @@
+package hub
+
+// base58Alphabet is the bitcoin base58 alphabet, used by Solana addresses.
+const base58Alphabet = "123456789ABCDEFGHJKLMNPQRSTUVWXYZabcdefghijkmnopqrstuvwxyz"
+
+// encodeBase58 function encodes the given bytes into a base58 string using
+// the bitcoin alphabet. The leading zero bytes are encoded as '1'.
+func encodeBase58(data []byte) string {
+	zeros := 0
+	for zeros < len(data) && data[zeros] == 0 {
+		zeros++
+	}
+	// convert the bytes to base 58 digits, stored in little endian
+	digits := make([]byte, 0, len(data)*138/100+1)
+	for _, b := range data[zeros:] {
+		carry := int(b)
+		for i := range digits {
+			carry += int(digits[i]) << 8
+			digits[i] = byte(carry % 58)
+			carry /= 58
+		}
+		for carry > 0 {
+			digits = append(digits, byte(carry%58))
+			carry /= 58
+		}
+	}
+	encoded := make([]byte, zeros+len(digits))
+	for i := 0; i < zeros; i++ {
+		encoded[i] = base58Alphabet[0]
+	}
+	for i, d := range digits {
+		encoded[len(encoded)-1-i] = base58Alphabet[d]
+	}
+	return string(encoded)
+}
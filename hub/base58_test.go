@@ -0,0 +1,31 @@
+package hub
+
+import (
+	"encoding/hex"
+	"strings"
+	"testing"
+
+	qt "github.com/frankban/quicktest"
+)
+
+func TestEncodeBase58(t *testing.T) {
+	tests := []struct {
+		hex      string
+		expected string
+	}{
+		{"", ""},
+		{"00", "1"},
+		{"48656c6c6f20576f726c6421", "2NEpo7TZRRrLZSi2U"},
+		// leading zero bytes
+		{"0000287fb4cd", "11233QC4"},
+		{strings.Repeat("00", 32), strings.Repeat("1", 32)},
+		// solana token program and wrapped sol addresses
+		{"06ddf6e1d765a193d9cbe146ceeb79ac1cb485ed5f5b37913a8cf5857eff00a9", "TokenkegQfeZyiNwAJbNbGKPFXCWuBvf9Ss623VQ5DA"},
+		{"069b8857feab8184fb687f634618c035dac439dc1aeb3b5598a0f00000000001", "So11111111111111111111111111111111111111112"},
+	}
+	for _, test := range tests {
+		data, err := hex.DecodeString(test.hex)
+		qt.Assert(t, err, qt.IsNil)
+		qt.Check(t, encodeBase58(data), qt.Equals, test.expected, qt.Commentf("hex: %s", test.hex))
+	}
+}
@@ -0,0 +1,126 @@
+package hub
+
+import (
+	"container/list"
+	"context"
+	"sync"
+	"time"
+)
+
+const (
+	// DefaultUserdataCacheSize is the number of users kept by the default
+	// user data cache of the hub API.
+	DefaultUserdataCacheSize = 4096
+	// DefaultUserdataCacheTTL is the time that the user data is kept by the
+	// default user data cache of the hub API.
+	DefaultUserdataCacheTTL = 10 * time.Minute
+)
+
+// UserdataCache interface defines the methods that a user data cache must
+// implement to be used by the hub API. It allows to use external stores to
+// share the cache between instances. The implementations must be safe for
+// concurrent use.
+type UserdataCache interface {
+	// Get returns the cached user data of the given FID and true, or false if
+	// it is not cached or it has expired.
+	Get(ctx context.Context, fid uint64) (*Userdata, bool)
+	// Set stores the user data of the given FID.
+	Set(ctx context.Context, fid uint64, userdata *Userdata)
+}
+
+// SetUserdataCache method sets the cache used by the API for the user data
+// lookups. By default, an in-memory LRUCache is used. If it is nil, the user
+// data is not cached.
+func (h *Hub) SetUserdataCache(cache UserdataCache) {
+	h.userdataCache = cache
+}
+
+// LRUCache struct is an in-memory UserdataCache that keeps up to a maximum
+// number of users, evicting the least recently used ones, for a fixed time.
+type LRUCache struct {
+	size  int
+	ttl   time.Duration
+	mtx   sync.Mutex
+	order *list.List
+	items map[uint64]*list.Element
+}
+
+type lruEntry struct {
+	fid      uint64
+	userdata *Userdata
+	expires  time.Time
+}
+
+var _ UserdataCache = (*LRUCache)(nil)
+
+// NewLRUCache function creates a new LRUCache that keeps up to the given
+// number of users for the given time. If the ttl is 0, the entries do not
+// expire.
+func NewLRUCache(size int, ttl time.Duration) *LRUCache {
+	return &LRUCache{
+		size:  max(size, 1),
+		ttl:   ttl,
+		order: list.New(),
+		items: map[uint64]*list.Element{},
+	}
+}
+
+// Get method returns the cached user data of the given FID and true, or false
+// if it is not cached or it has expired.
+func (c *LRUCache) Get(_ context.Context, fid uint64) (*Userdata, bool) {
+	c.mtx.Lock()
+	defer c.mtx.Unlock()
+	elem, ok := c.items[fid]
+	if !ok {
+		return nil, false
+	}
+	entry := elem.Value.(*lruEntry)
+	if c.ttl > 0 && time.Now().After(entry.expires) {
+		c.order.Remove(elem)
+		delete(c.items, fid)
+		return nil, false
+	}
+	c.order.MoveToFront(elem)
+	return entry.userdata, true
+}
+
+// Set method stores the user data of the given FID, evicting the least
+// recently used entry if the cache is full.
+func (c *LRUCache) Set(_ context.Context, fid uint64, userdata *Userdata) {
+	c.mtx.Lock()
+	defer c.mtx.Unlock()
+	entry := &lruEntry{fid: fid, userdata: userdata, expires: time.Now().Add(c.ttl)}
+	if elem, ok := c.items[fid]; ok {
+		elem.Value = entry
+		c.order.MoveToFront(elem)
+		return
+	}
+	c.items[fid] = c.order.PushFront(entry)
+	if c.order.Len() > c.size {
+		oldest := c.order.Back()
+		c.order.Remove(oldest)
+		delete(c.items, oldest.Value.(*lruEntry).fid)
+	}
+}
+
+// copy method returns a copy of the user data, to avoid sharing the cached
+// one with the callers.
+func (u *Userdata) copy() *Userdata {
+	userdata := *u
+	userdata.VerificationsAddresses = append([]string{}, u.VerificationsAddresses...)
+	userdata.SolanaAddresses = append([]string{}, u.SolanaAddresses...)
+	userdata.Verifications = make([]*Verification, 0, len(u.Verifications))
+	for _, verification := range u.Verifications {
+		v := *verification
+		userdata.Verifications = append(userdata.Verifications, &v)
+	}
+	userdata.Signers = append([]string{}, u.Signers...)
+	if u.Profile != nil {
+		userdata.Profile = make(map[string]*UserdataField, len(u.Profile))
+		for dataType, field := range u.Profile {
+			f := *field
+			userdata.Profile[dataType] = &f
+		}
+	}
+	return &userdata
+}
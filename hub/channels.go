@@ -0,0 +1,76 @@
+package hub
+
+import (
+	"context"
+	"fmt"
+	"net/url"
+
+	hubproto "github.com/vocdoni/farcaster-go/hub/proto"
+	"go.vocdoni.io/dvote/log"
+)
+
+// ChannelResolver interface defines the methods that a channel metadata
+// provider must implement to be used by the hub API, since the channels are
+// not part of the protocol. The NeynarAPI satisfies this interface.
+type ChannelResolver interface {
+	Channel(ctx context.Context, channelID string) (*Channel, error)
+}
+
+// SetChannelResolver method sets the resolver used to get the channels
+// metadata. If it is nil, the channel metadata methods return
+// ErrNotSupported.
+func (h *Hub) SetChannelResolver(resolver ChannelResolver) {
+	h.channels = resolver
+}
+
+// PublishToChannel method sends a new cast with the given content and embeds
+// to the channel with the given parent URL (the URL of the Channel).
+func (h *Hub) PublishToChannel(ctx context.Context, parentURL, content string,
+	mentionFIDs []uint64, embeds ...string,
+) error {
+	log.Infow("publishing cast to channel", "channel", parentURL, "msg", content,
+		"embeds", embeds, "mentions", mentionFIDs)
+	if parentURL == "" || len([]byte(parentURL)) > MaxURLBytes {
+		return fmt.Errorf("invalid channel url")
+	}
+	// check if the content is too long
+	if len([]byte(content)) > MaxCastBytes {
+		return fmt.Errorf("content is too long")
+	}
+	// create the cast add body and set the channel as parent
+	castBody, err := h.newAddCastBody(ctx, content, mentionFIDs, embeds...)
+	if err != nil {
+		return fmt.Errorf("error decomposing content: %w", err)
+	}
+	castBody.Parent = &hubproto.CastAddBody_ParentUrl{ParentUrl: parentURL}
+	msgBytes, err := h.buildAndSignAddCastBody(castBody)
+	if err != nil {
+		return fmt.Errorf("error building and signing cast body: %w", err)
+	}
+	return h.submitMessage(ctx, msgBytes)
+}
+
+// ChannelCasts method returns the casts published in the channel with the
+// given parent URL (the URL of the Channel), following the given page
+// options. It returns the messages, the token to request the next page (empty
+// if there are no more pages) and an error.
+func (h *Hub) ChannelCasts(ctx context.Context, parentURL string, opts *PageOptions) ([]*APIMessage, string, error) {
+	if parentURL == "" {
+		return nil, "", fmt.Errorf("invalid channel url")
+	}
+	messages := []*APIMessage{}
+	uri := fmt.Sprintf(ENDPOINT_CASTS_BY_PARENT_URL, url.QueryEscape(parentURL))
+	nextPageToken, err := h.iterateMessages(ctx, uri, opts, func(m *hubMessage) bool {
+		message, err := h.apiMessage(ctx, m)
+		if err != nil {
+			log.Warnw("invalid cast", "hash", m.HexHash, "error", err)
+			return true
+		}
+		messages = append(messages, message)
+		return !opts.limitReached(len(messages))
+	})
+	if err != nil {
+		return nil, "", fmt.Errorf("error downloading channel casts: %w", err)
+	}
+	return messages, nextPageToken, nil
+}
@@ -0,0 +1,217 @@
+package hub
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"go.vocdoni.io/dvote/log"
+)
+
+// HubEventType is the type of the events emitted by the hub.
+type HubEventType string
+
+const (
+	// HubEventMergeMessage is emitted when a message is merged into the hub.
+	HubEventMergeMessage HubEventType = "HUB_EVENT_TYPE_MERGE_MESSAGE"
+	// HubEventPruneMessage is emitted when a message is pruned from the hub
+	// because the user exceeded its storage limits.
+	HubEventPruneMessage HubEventType = "HUB_EVENT_TYPE_PRUNE_MESSAGE"
+	// HubEventRevokeMessage is emitted when a message is revoked from the hub
+	// because its signer was removed.
+	HubEventRevokeMessage HubEventType = "HUB_EVENT_TYPE_REVOKE_MESSAGE"
+
+	// eventsPollInterval is the time to wait before requesting new events
+	// when there are no more events to process
+	eventsPollInterval = 2 * time.Second
+	// eventsMaxBackoff is the maximum time to wait before retrying to request
+	// the events after a failure
+	eventsMaxBackoff = time.Minute
+	// eventsRequestTimeout is the timeout of every events request
+	eventsRequestTimeout = 15 * time.Second
+)
+
+// HubEvent is a struct that represents an event emitted by the hub, including
+// its ID, its type, the message affected and, for merge events, the messages
+// deleted by the merge.
+type HubEvent struct {
+	ID              uint64
+	Type            HubEventType
+	Message         *EventMessage
+	DeletedMessages []*EventMessage
+}
+
+// EventMessage is a struct that represents a message included in a hub
+// event. Mentions are only included for cast add messages. The timestamp is a
+// unix timestamp.
+type EventMessage struct {
+	Type      string
+	FID       uint64
+	Timestamp uint64
+	Hash      string
+	Signer    string
+	Mentions  []uint64
+	raw       *hubMessage
+}
+
+// IsCast method returns if the event message is a cast add message.
+func (m *EventMessage) IsCast() bool {
+	return m.raw != nil && m.raw.Data.Type == MESSAGE_TYPE_CAST_ADD && m.raw.Data.CastAddBody != nil
+}
+
+// Subscribe method subscribes to the events of the hub starting from the given
+// event ID (if it is 0, from the oldest event kept by the hub) and delivers
+// them through the returned channel. If any event type is provided, only the
+// events of those types are delivered. It polls the hub events, waiting a few
+// seconds when there are no new events, and if a request fails, it retries
+// with an exponential backoff resuming from the last event processed. The
+// channel is closed when the given context is done. The event IDs are
+// assigned by every hub, so with a pool of hubs, the events could be
+// delivered again or skipped if the requests are routed to another hub.
+func (h *Hub) Subscribe(ctx context.Context, fromEventID uint64, eventTypes ...HubEventType) (<-chan *HubEvent, error) {
+	filter := map[HubEventType]bool{}
+	for _, eventType := range eventTypes {
+		switch eventType {
+		case HubEventMergeMessage, HubEventPruneMessage, HubEventRevokeMessage:
+			filter[eventType] = true
+		default:
+			return nil, fmt.Errorf("unsupported event type: %s", eventType)
+		}
+	}
+	events := make(chan *HubEvent)
+	go func() {
+		defer close(events)
+		nextEventID := fromEventID
+		backoff := eventsPollInterval
+		for {
+			res, err := h.events(ctx, nextEventID)
+			if err != nil {
+				if ctx.Err() != nil {
+					return
+				}
+				log.Warnw("error getting hub events, retrying", "from", nextEventID, "error", err, "backoff", backoff)
+				if !sleepContext(ctx, backoff) {
+					return
+				}
+				backoff = min(backoff*2, eventsMaxBackoff)
+				continue
+			}
+			backoff = eventsPollInterval
+			for _, rawEvent := range res.Events {
+				// update the next event to resume from the last one processed
+				nextEventID = rawEvent.ID + 1
+				event := h.hubEvent(rawEvent)
+				if event == nil || (len(filter) > 0 && !filter[event.Type]) {
+					continue
+				}
+				select {
+				case events <- event:
+				case <-ctx.Done():
+					return
+				}
+			}
+			// if there are no more events, wait before requesting again
+			if len(res.Events) == 0 || res.NextPageEventID == 0 {
+				if !sleepContext(ctx, eventsPollInterval) {
+					return
+				}
+				continue
+			}
+			nextEventID = max(nextEventID, res.NextPageEventID)
+		}
+	}()
+	return events, nil
+}
+
+// events method requests the hub events starting from the given event ID.
+func (h *Hub) events(ctx context.Context, fromEventID uint64) (*hubEventsResponse, error) {
+	internalCtx, cancel := context.WithTimeout(ctx, eventsRequestTimeout)
+	defer cancel()
+	res := &hubEventsResponse{}
+	if err := h.getJSON(internalCtx, fmt.Sprintf(ENDPOINT_EVENTS, fromEventID), res); err != nil {
+		return nil, fmt.Errorf("error downloading events: %w", err)
+	}
+	return res, nil
+}
+
+// hubEvent method converts the given raw hub event into a HubEvent. It
+// returns nil if the event is not a supported message event or its message is
+// not intended for the network of the API.
+func (h *Hub) hubEvent(rawEvent *hubEvent) *HubEvent {
+	var body *hubEventBody
+	switch HubEventType(rawEvent.Type) {
+	case HubEventMergeMessage:
+		body = rawEvent.MergeMessageBody
+	case HubEventPruneMessage:
+		body = rawEvent.PruneMessageBody
+	case HubEventRevokeMessage:
+		body = rawEvent.RevokeMessageBody
+	}
+	if body == nil || body.Message == nil || body.Message.Data == nil {
+		return nil
+	}
+	if err := h.checkNetwork(body.Message); err != nil {
+		log.Warnw("discarding event", "id", rawEvent.ID, "error", err)
+		return nil
+	}
+	event := &HubEvent{
+		ID:              rawEvent.ID,
+		Type:            HubEventType(rawEvent.Type),
+		Message:         newEventMessage(body.Message),
+		DeletedMessages: []*EventMessage{},
+	}
+	for _, deleted := range body.DeletedMessages {
+		if deleted.Data != nil {
+			event.DeletedMessages = append(event.DeletedMessages, newEventMessage(deleted))
+		}
+	}
+	return event
+}
+
+// EventCast method returns the cast of the given event message as an
+// APIMessage, composing its content with the mentions usernames. It returns
+// an error if the message is not a cast add.
+func (h *Hub) EventCast(ctx context.Context, msg *EventMessage) (*APIMessage, error) {
+	if !msg.IsCast() {
+		return nil, fmt.Errorf("no valid cast")
+	}
+	message, err := h.apiMessage(ctx, msg.raw)
+	if err != nil {
+		return nil, err
+	}
+	for _, fid := range msg.Mentions {
+		if fid == h.fid {
+			message.IsMention = true
+			break
+		}
+	}
+	return message, nil
+}
+
+// newEventMessage function creates a new EventMessage from the given raw hub
+// message.
+func newEventMessage(msg *hubMessage) *EventMessage {
+	eventMsg := &EventMessage{
+		Type:      msg.Data.Type,
+		FID:       msg.Data.From,
+		Timestamp: msg.Data.Timestamp + farcasterEpoch,
+		Hash:      msg.HexHash,
+		Signer:    msg.Signer,
+		raw:       msg,
+	}
+	if msg.Data.CastAddBody != nil {
+		eventMsg.Mentions = msg.Data.CastAddBody.Mentions
+	}
+	return eventMsg
+}
+
+// sleepContext function waits the given duration or until the given context
+// is done. It returns false if the context is done.
+func sleepContext(ctx context.Context, d time.Duration) bool {
+	select {
+	case <-time.After(d):
+		return true
+	case <-ctx.Done():
+		return false
+	}
+}
@@ -2,31 +2,44 @@ package hub
 
 import (
 	"context"
-	"crypto/ed25519"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
 	"fmt"
 	"io"
 	"net/http"
+	"net/url"
 	"regexp"
+	"strings"
 	"time"
+	"unicode"
+	"unicode/utf8"
 
 	hubproto "github.com/vocdoni/farcaster-go/hub/proto"
-	"github.com/zeebo/blake3"
-	"google.golang.org/protobuf/proto"
+	"go.vocdoni.io/dvote/log"
+	"golang.org/x/sync/errgroup"
 )
 
-var mentionRgx = regexp.MustCompile(`(@\S+)`)
+// mentionRgx matches the mentions of the content, an @ followed by a fname or
+// an ENS name (including subdomains). The trailing punctuation is not part of
+// the mention.
+var mentionRgx = regexp.MustCompile(`@([a-zA-Z0-9][a-zA-Z0-9_-]*(?:\.[a-zA-Z0-9_-]+)*)`)
 
 // newAddCastBody method creates a new cast add body with the given content, the
 // mentions fids and the embeds. It returns the cast add body and an error. It
 // returns an error if the farcaster user is not set or there is an error
 // decomposing the content. It replaces the mentions with the usernames and
-// creates the cast add body with the mentions positions and the embeds.
-func (h *Hub) newAddCastBody(content string, mentionFIDs []uint64, embeds ...string) (*hubproto.CastAddBody, error) {
+// creates the cast add body with the mentions positions and the embeds. If no
+// mentions fids are provided, the mentions of the content are resolved by
+// their usernames.
+func (h *Hub) newAddCastBody(ctx context.Context, content string, mentionFIDs []uint64,
+	embeds ...string,
+) (*hubproto.CastAddBody, error) {
 	if h.fid == 0 {
 		return nil, fmt.Errorf("no farcaster user set")
 	}
 	// decompose the content and the mentions
-	castBody, err := h.decomposeContent(content, mentionFIDs)
+	castBody, err := h.decomposeContent(ctx, content, mentionFIDs)
 	if err != nil {
 		return nil, fmt.Errorf("error decomposing content: %s", err)
 	}
@@ -53,64 +66,112 @@ func (h *Hub) newAddCastBody(content string, mentionFIDs []uint64, embeds ...str
 	return castAdd, nil
 }
 
-// buildAndSignAddCastBody method builds and signs the given cast add body. It
-// returns the message bytes and an error. It creates the message data with the
-// message type, the bot FID, the current timestamp, the network, and the cast
-// add body. It marshals the message data and calculates the hash of the message
-// data. It creates the message with the hash scheme, the hash and the signature
-// scheme. It signs the message with the private key and sets the signature and
-// the signer to the message. It marshals the message and returns the message
-// bytes.
+// buildAndSignAddCastBody method builds and signs a cast add message with the
+// given cast add body. It returns the message bytes and an error.
 func (h *Hub) buildAndSignAddCastBody(castAddBody *hubproto.CastAddBody) ([]byte, error) {
-	// compose the message data with the message type, the bot FID, the current
-	// timestamp, the network, and the cast add body
-	msgData := &hubproto.MessageData{
-		Type:      hubproto.MessageType_MESSAGE_TYPE_CAST_ADD,
-		Fid:       h.fid,
-		Timestamp: uint32(uint64(time.Now().Unix()) - farcasterEpoch),
-		Network:   hubproto.FarcasterNetwork_FARCASTER_NETWORK_MAINNET,
-		Body:      &hubproto.MessageData_CastAddBody{CastAddBody: castAddBody},
-	}
-	// marshal the message data
-	msgDataBytes, err := proto.Marshal(msgData)
-	if err != nil {
-		return nil, fmt.Errorf("error marshalling message data: %s", err)
-	}
-	// calculate the hash of the message data
-	hasher := blake3.New()
-	hasher.Write(msgDataBytes)
-	hash := hasher.Sum(nil)[:20]
-	// create the message with the hash scheme, the hash and the signature
-	// scheme
-	msg := &hubproto.Message{
-		HashScheme:      hubproto.HashScheme_HASH_SCHEME_BLAKE3,
-		Hash:            hash,
-		SignatureScheme: hubproto.SignatureScheme_SIGNATURE_SCHEME_ED25519,
-		Data:            msgData,
-		DataBytes:       msgDataBytes,
-	}
-	// sign the message with the private key
-	signature := ed25519.Sign(h.signer, hash)
-	signer := h.signer.Public().(ed25519.PublicKey)
-	// set the signature and the signer to the message
-	msg.Signature = signature
-	msg.Signer = signer
-	// marshal the message
-	msgBytes, err := proto.Marshal(msg)
+	return h.buildAndSignMessage(&hubproto.MessageData{
+		Type: hubproto.MessageType_MESSAGE_TYPE_CAST_ADD,
+		Body: &hubproto.MessageData_CastAddBody{CastAddBody: castAddBody},
+	})
+}
+
+// buildAndSignMessage method builds and signs the given message data. It
+// returns the message bytes and an error. The message data must include the
+// message type and the body, the rest of the fields (the bot FID, the current
+// timestamp and the network) are filled by this method before building the
+// message with BuildMessage and the configured signer.
+func (h *Hub) buildAndSignMessage(msgData *hubproto.MessageData) ([]byte, error) {
+	if h.fid == 0 || h.signer == nil {
+		return nil, fmt.Errorf("no farcaster user set")
+	}
+	// complete the message data with the bot FID, the current timestamp and
+	// the network
+	msgData.Fid = h.fid
+	msgData.Timestamp = FarcasterTimestamp(time.Now())
+	msgData.Network = h.network
+	_, msgBytes, err := BuildMessage(msgData, h.signer)
 	if err != nil {
-		return nil, fmt.Errorf("error marshalling message: %s", err)
+		return nil, err
 	}
 	return msgBytes, nil
 }
 
+// submitMessage method submits the given message bytes to the hub. It returns
+// an error if something goes wrong or the hub rejects the message.
+func (h *Hub) submitMessage(ctx context.Context, msgBytes []byte) error {
+	// create a new context with a timeout
+	internalCtx, cancel := context.WithTimeout(ctx, submitMessageTimeout)
+	defer cancel()
+	// submit the message to the hubs of the API
+	res, err := h.do(internalCtx, http.MethodPost, ENDPOINT_SUBMIT_MESSAGE, msgBytes, "application/octet-stream")
+	if err != nil {
+		return fmt.Errorf("error submitting the message: %w", err)
+	}
+	defer res.Body.Close()
+	if res.StatusCode != http.StatusOK {
+		return newHubError(res)
+	}
+	return nil
+}
+
+// newHubError function creates a new HubError with the information of the
+// given hub response. It tries to decode the error code and the details from
+// the response body, if it fails, it uses the raw body as details.
+func newHubError(res *http.Response) *HubError {
+	hubErr := &HubError{StatusCode: res.StatusCode}
+	body, err := io.ReadAll(res.Body)
+	if err != nil {
+		hubErr.Details = res.Status
+		return hubErr
+	}
+	errResponse := &hubErrorResponse{}
+	if err := json.Unmarshal(body, errResponse); err != nil || errResponse.ErrCode == "" {
+		hubErr.Details = string(body)
+		return hubErr
+	}
+	hubErr.Code = errResponse.ErrCode
+	hubErr.Details = errResponse.Details
+	return hubErr
+}
+
 // composeCastContent method composes the cast content with the given body. It
 // returns the content and an error. If the body is nil, it returns an empty
 // string and no error. If the body is not nil, it replaces the mentions with
-// the usernames and returns the content.
-func (h *Hub) composeCastContent(body *hubCastAddBody) (string, error) {
+// the usernames and returns the content. The user data of the mentioned users
+// is requested concurrently, once per user.
+func (h *Hub) composeCastContent(ctx context.Context, body *hubCastAddBody) (string, error) {
 	if body == nil {
 		return "", nil
 	}
+	// get the unique fids of the mentioned users, skipping the mention of
+	// the user at the beginning of a reply
+	fids := []uint64{}
+	indexes := map[uint64]int{}
+	for i, fid := range body.Mentions {
+		if _, ok := indexes[fid]; ok || (body.MentionsPositions[i] == 0 && fid == h.fid) {
+			continue
+		}
+		indexes[fid] = len(fids)
+		fids = append(fids, fid)
+	}
+	// get their usernames concurrently, every worker writes only its own
+	// position of the results
+	usernames := make([]string, len(fids))
+	group, groupCtx := errgroup.WithContext(ctx)
+	group.SetLimit(maxConcurrentUserdataRequests)
+	for i, fid := range fids {
+		group.Go(func() error {
+			user, err := h.UserDataByFID(groupCtx, fid)
+			if err != nil {
+				return err
+			}
+			usernames[i] = user.Username
+			return nil
+		})
+	}
+	if err := group.Wait(); err != nil {
+		return "", err
+	}
 	content := body.Text
 	for i := len(body.Mentions) - 1; i >= 0; i-- {
 		fid := body.Mentions[i]
@@ -118,68 +179,258 @@ func (h *Hub) composeCastContent(body *hubCastAddBody) (string, error) {
 		if pos == 0 && fid == h.fid {
 			continue
 		}
-		user, err := h.UserDataByFID(context.Background(), fid)
-		if err != nil {
-			return "", err
-		}
-		content = content[:pos] + "@" + user.Username + content[pos:]
+		content = content[:pos] + "@" + usernames[indexes[fid]] + content[pos:]
 	}
 	return content, nil
 }
 
 // decomposeContent method decomposes the content with the given mentions. It
-// returns the body and an error. If the mentions are not the same length as
-// the fids, it returns an error. If the mentions are the same length as the
-// fids, it calculates the position of the mentions, removes them from the
-// content, and returns the body.
-func (h *Hub) decomposeContent(content string, mentionFids []uint64) (*hubCastAddBody, error) {
+// returns the body and an error. If mentions fids are provided, they must be
+// the same length as the mentions of the content, in the same order, or it
+// returns an error. If no mentions fids are provided, the username of every
+// mention is resolved to its fid, and the mentions of unknown usernames are
+// left as plain text. It calculates the position (in bytes) of the mentions,
+// removes them from the content, and returns the body.
+func (h *Hub) decomposeContent(ctx context.Context, content string, mentionFids []uint64) (*hubCastAddBody, error) {
 	// get the mentions from the content
-	usernames := mentionRgx.FindAllString(content, -1)
-	if len(usernames) == 0 {
+	mentions := findMentions(content)
+	if len(mentions) == 0 {
 		return &hubCastAddBody{
 			Text: content,
 		}, nil
 	}
-	// check if the mentions have the same length as the fids provided
-	if len(mentionFids) != len(usernames) {
+	fids := mentionFids
+	if len(fids) == 0 {
+		// resolve the usernames of the mentions, the unknown ones are kept as
+		// plain text (fid 0)
+		fids = make([]uint64, len(mentions))
+		resolved := map[string]uint64{}
+		resolve := func(username string) (uint64, error) {
+			username = strings.ToLower(username)
+			if fid, ok := resolved[username]; ok {
+				return fid, nil
+			}
+			fid, err := h.usernameFID(ctx, username)
+			if err != nil {
+				return 0, fmt.Errorf("error resolving mention %s: %w", username, err)
+			}
+			resolved[username] = fid
+			return fid, nil
+		}
+		for i, mention := range mentions {
+			fid, err := resolve(content[mention[2]:mention[3]])
+			if err != nil {
+				return nil, err
+			}
+			// if a dotted username is unknown, the dot could be part of the
+			// text (like "@alice.Thanks"), so try with the first part only
+			if dot := strings.IndexByte(content[mention[2]:mention[3]], '.'); fid == 0 && dot > 0 {
+				if fid, err = resolve(content[mention[2] : mention[2]+dot]); err != nil {
+					return nil, err
+				}
+				if fid != 0 {
+					mention[1] = mention[2] + dot
+				}
+			}
+			fids[i] = fid
+		}
+	} else if len(fids) != len(mentions) {
+		// check if the mentions have the same length as the fids provided
 		return nil, fmt.Errorf("invalid mentions")
 	}
-	// get the positions of the mentions in the content
-	usernamePositions := mentionRgx.FindAllStringIndex(content, -1)
-	mentionsPositions := make([]uint64, len(mentionFids))
-	removedChars := 0
-	for i, username := range usernames {
-		mentionsPositions[i] = uint64(usernamePositions[i][0])
-		if i > 0 {
-			mentionsPositions[i] -= uint64(removedChars)
+	// remove the mentions from the content and calculate their positions in
+	// the resulting text
+	body := &hubCastAddBody{
+		Mentions:          []uint64{},
+		MentionsPositions: []uint64{},
+	}
+	text := strings.Builder{}
+	last := 0
+	for i, mention := range mentions {
+		if fids[i] == 0 {
+			continue
+		}
+		text.WriteString(content[last:mention[0]])
+		body.Mentions = append(body.Mentions, fids[i])
+		body.MentionsPositions = append(body.MentionsPositions, uint64(text.Len()))
+		last = mention[1]
+	}
+	text.WriteString(content[last:])
+	body.Text = text.String()
+	return body, nil
+}
+
+// findMentions function returns the indexes of the mentions of the given
+// content. Every mention includes the start and the end of the whole mention
+// and of the username, as regexp.FindAllStringSubmatchIndex does. The @ that
+// follow a letter, a number or another symbol (like in email addresses) are
+// not mentions.
+func findMentions(content string) [][]int {
+	mentions := [][]int{}
+	for _, mention := range mentionRgx.FindAllStringSubmatchIndex(content, -1) {
+		if mention[0] > 0 {
+			prev, _ := utf8.DecodeLastRuneInString(content[:mention[0]])
+			if unicode.IsLetter(prev) || unicode.IsDigit(prev) || strings.ContainsRune("_-.@", prev) {
+				continue
+			}
 		}
-		removedChars += len(username)
-	}
-	// remove mentions from the content
-	content = mentionRgx.ReplaceAllString(content, "")
-	// build the body with the cleaned content and the mentions
-	return &hubCastAddBody{
-		Text:              content,
-		Mentions:          mentionFids,
-		MentionsPositions: mentionsPositions,
-	}, nil
+		mentions = append(mentions, mention)
+	}
+	return mentions
 }
 
-// newRequest method creates a new http request with the given method, uri and
-// body. It returns the request and an error.
-func (h *Hub) newRequest(ctx context.Context, method string, uri string, body io.Reader) (*http.Request, error) {
-	endpoint := fmt.Sprintf("%s/%s", h.endpoint, uri)
-	req, err := http.NewRequestWithContext(ctx, method, endpoint, body)
+// usernameFID method returns the fid of the user with the given username
+// (fname or ENS name) using its username proof. If the username is unknown,
+// it returns 0 and no error.
+func (h *Hub) usernameFID(ctx context.Context, username string) (uint64, error) {
+	proof, err := h.UserByUsername(ctx, username)
 	if err != nil {
-		return nil, fmt.Errorf("error creating request: %w", err)
+		if errors.Is(err, ErrNotFound) {
+			return 0, nil
+		}
+		return 0, err
 	}
-	if h.auth != nil {
-		for k, v := range h.auth {
-			if k == "" || v == "" {
+	return proof.Fid, nil
+}
+
+// getJSON method performs a GET request to the given uri of the hub and
+// decodes the JSON response into the given value. If the hub responds with an
+// error, it returns a *HubError.
+func (h *Hub) getJSON(ctx context.Context, uri string, value any) error {
+	res, err := h.do(ctx, http.MethodGet, uri, nil, "")
+	if err != nil {
+		return fmt.Errorf("error downloading json: %w", err)
+	}
+	defer res.Body.Close()
+	return decodeJSONResponse(res, value)
+}
+
+// decodeJSONResponse function decodes the JSON body of the given hub response
+// into the given value. If the hub responded with an error, it returns a
+// *HubError.
+func decodeJSONResponse(res *http.Response, value any) error {
+	if res.StatusCode != http.StatusOK {
+		return newHubError(res)
+	}
+	body, err := io.ReadAll(res.Body)
+	if err != nil {
+		return fmt.Errorf("error reading response body: %w", err)
+	}
+	if err := json.Unmarshal(body, value); err != nil {
+		return fmt.Errorf("error unmarshalling json: %w", err)
+	}
+	return nil
+}
+
+// iterateMessages method iterates over the messages returned by the given list
+// endpoint uri, requesting the pages following the given page options (the
+// limit is not applied here). It calls fn for every message until it returns
+// false or there are no more pages. It returns the token of the page to
+// resume the iteration or an empty string if there are no more pages. If the
+// iteration is stopped in the middle of a page, the token returned is the one
+// of that page, so resuming it could return some messages again.
+func (h *Hub) iterateMessages(ctx context.Context, uri string, opts *PageOptions, fn func(*hubMessage) bool) (string, error) {
+	if opts == nil {
+		opts = &PageOptions{}
+	}
+	// include the page size and the order in the uri
+	if opts.PageSize > 0 {
+		uri += fmt.Sprintf("&pageSize=%d", opts.PageSize)
+	}
+	if opts.Reverse {
+		uri += "&reverse=true"
+	}
+	pageToken := opts.PageToken
+	for {
+		pageURI := uri
+		if pageToken != "" {
+			pageURI += "&pageToken=" + url.QueryEscape(pageToken)
+		}
+		res := &hubMessageResponse{}
+		if err := h.getJSON(ctx, pageURI, res); err != nil {
+			return pageToken, err
+		}
+		for i, msg := range res.Messages {
+			if msg.Data == nil {
 				continue
 			}
-			req.Header.Set(k, v)
+			if err := h.checkNetwork(msg); err != nil {
+				log.Warnw("discarding message", "hash", msg.HexHash, "error", err)
+				continue
+			}
+			if !fn(msg) {
+				// if it stops in the last message of the page, the iteration
+				// can be resumed from the next page
+				if i == len(res.Messages)-1 {
+					return res.NextPageToken, nil
+				}
+				return pageToken, nil
+			}
 		}
+		if res.NextPageToken == "" {
+			return "", nil
+		}
+		pageToken = res.NextPageToken
+	}
+}
+
+// checkNetwork method checks that the given hub message is intended for the
+// network of the API, if the message includes it. It returns an error that
+// matches ErrNetworkMismatch if it is not.
+func (h *Hub) checkNetwork(msg *hubMessage) error {
+	if msg.Data == nil || msg.Data.Network == "" || msg.Data.Network == h.network.String() {
+		return nil
+	}
+	return fmt.Errorf("%w: %s (%s expected)", ErrNetworkMismatch, msg.Data.Network, h.network.String())
+}
+
+// apiMessage method converts the given hub cast add message into an
+// APIMessage, composing its content with the mentions usernames and parsing
+// its embeds and parent. It returns an error if the message is not a cast add.
+func (h *Hub) apiMessage(ctx context.Context, msg *hubMessage) (*APIMessage, error) {
+	if msg.Data == nil || msg.Data.Type != MESSAGE_TYPE_CAST_ADD || msg.Data.CastAddBody == nil {
+		return nil, fmt.Errorf("no valid cast")
+	}
+	// compose the content of the message
+	content, err := h.composeCastContent(ctx, msg.Data.CastAddBody)
+	if err != nil {
+		log.Error(err)
+	}
+	// parse the embeds of the message to be included
+	embeds := []string{}
+	castEmbeds := []*CastID{}
+	for _, e := range msg.Data.CastAddBody.Embeds {
+		if e.CastID != nil {
+			castEmbeds = append(castEmbeds, &CastID{FID: e.CastID.FID, Hash: e.CastID.Hash})
+			continue
+		}
+		embeds = append(embeds, e.Url)
+	}
+	// check if the message has a parent
+	var parent *ParentAPIMessage = nil
+	if msg.Data.CastAddBody.ParentCast != nil {
+		parent = &ParentAPIMessage{
+			FID:  msg.Data.CastAddBody.ParentCast.FID,
+			Hash: msg.Data.CastAddBody.ParentCast.Hash,
+		}
+	}
+	return &APIMessage{
+		Content:    content,
+		Author:     msg.Data.From,
+		Hash:       msg.HexHash,
+		Parent:     parent,
+		ParentURL:  msg.Data.CastAddBody.ParentURL,
+		Embeds:     embeds,
+		CastEmbeds: castEmbeds,
+	}, nil
+}
+
+// decodeHash function decodes the given hexadecimal message hash, with or
+// without the 0x prefix. It returns the hash bytes and an error.
+func decodeHash(hash string) ([]byte, error) {
+	bHash, err := hex.DecodeString(strings.TrimPrefix(hash, "0x"))
+	if err != nil {
+		return nil, fmt.Errorf("error decoding hash: %w", err)
 	}
-	return req, nil
+	return bHash, nil
 }
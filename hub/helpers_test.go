@@ -0,0 +1,176 @@
+package hub
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	qt "github.com/frankban/quicktest"
+)
+
+// newTestHub function starts a stub hub that serves the given handler and
+// returns a Hub connected to it. The stub is closed when the test ends.
+func newTestHub(t *testing.T, handler http.HandlerFunc) *Hub {
+	srv := httptest.NewServer(handler)
+	t.Cleanup(srv.Close)
+	h, err := NewHubAPI(srv.URL, nil)
+	qt.Assert(t, err, qt.IsNil)
+	return h
+}
+
+// writeJSON function writes the given value as the JSON response of a stub
+// hub, or a hub not found error if it is nil.
+func writeJSON(w http.ResponseWriter, value any) {
+	if value == nil {
+		w.WriteHeader(http.StatusNotFound)
+		value = &hubErrorResponse{ErrCode: hubErrCodeNotFound, Details: "not found"}
+	}
+	_ = json.NewEncoder(w).Encode(value)
+}
+
+// usernamesHandler function returns a stub hub handler that serves the
+// username proofs of the given usernames.
+func usernamesHandler(fids map[string]uint64) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		name := r.URL.Query().Get("name")
+		fid, ok := fids[name]
+		if r.URL.Path != "/userNameProofByName" || !ok {
+			writeJSON(w, nil)
+			return
+		}
+		writeJSON(w, &usernameProofs{
+			Username:       name,
+			CustodyAddress: "0x0000000000000000000000000000000000000001",
+			FID:            fid,
+			Type:           MESSAGE_TYPE_USERPROOF,
+		})
+	}
+}
+
+func TestFindMentions(t *testing.T) {
+	tests := []struct {
+		content  string
+		expected []string
+	}{
+		{"hello @alice", []string{"alice"}},
+		{"@alice, @bob.eth and @carol_1.", []string{"alice", "bob.eth", "carol_1"}},
+		{"(@alice)", []string{"alice"}},
+		{"¡@alice", []string{"alice"}},
+		{"héllo @alice, ok", []string{"alice"}},
+		{"write to alice@vocdoni.io", []string{}},
+		{"a@alice, é@alice, _@alice, .@alice, @@alice", []string{}},
+		{"@ alone", []string{}},
+	}
+	for _, test := range tests {
+		usernames := []string{}
+		for _, mention := range findMentions(test.content) {
+			usernames = append(usernames, test.content[mention[2]:mention[3]])
+		}
+		qt.Check(t, usernames, qt.DeepEquals, test.expected, qt.Commentf("content: %q", test.content))
+	}
+}
+
+func TestDecomposeContent(t *testing.T) {
+	h := newTestHub(t, usernamesHandler(map[string]uint64{"alice": 7, "bob.eth": 9}))
+
+	tests := []struct {
+		content   string
+		fids      []uint64
+		text      string
+		mentions  []uint64
+		positions []uint64
+	}{
+		{"hi @alice, ok", nil, "hi , ok", []uint64{7}, []uint64{3}},
+		{"bye @alice.", nil, "bye .", []uint64{7}, []uint64{4}},
+		{"(@alice)", nil, "()", []uint64{7}, []uint64{1}},
+		{"@alice.Thanks", nil, ".Thanks", []uint64{7}, []uint64{0}},
+		{"@bob.eth and @ALICE", nil, " and ", []uint64{9, 7}, []uint64{0, 5}},
+		// the positions are in bytes
+		{"¡@alice", nil, "¡", []uint64{7}, []uint64{2}},
+		{"héllo @alice, ok", nil, "héllo , ok", []uint64{7}, []uint64{7}},
+		// emails and unknown usernames are kept as plain text
+		{"write to bob@alice.io", nil, "write to bob@alice.io", nil, nil},
+		{"hi @carol and @alice", nil, "hi @carol and ", []uint64{7}, []uint64{14}},
+		// the fids provided are not resolved
+		{"hi @carol", []uint64{3}, "hi ", []uint64{3}, []uint64{3}},
+	}
+	for _, test := range tests {
+		comment := qt.Commentf("content: %q", test.content)
+		body, err := h.decomposeContent(context.Background(), test.content, test.fids)
+		qt.Assert(t, err, qt.IsNil, comment)
+		qt.Check(t, body.Text, qt.Equals, test.text, comment)
+		qt.Assert(t, body.Mentions, qt.HasLen, len(test.mentions), comment)
+		for i := range test.mentions {
+			qt.Check(t, body.Mentions[i], qt.Equals, test.mentions[i], comment)
+			qt.Check(t, body.MentionsPositions[i], qt.Equals, test.positions[i], comment)
+		}
+	}
+
+	// the fids provided must match the mentions
+	_, err := h.decomposeContent(context.Background(), "@alice and @bob.eth", []uint64{7})
+	qt.Assert(t, err, qt.IsNotNil)
+	// the hub errors are returned
+	h = newTestHub(t, func(w http.ResponseWriter, _ *http.Request) {
+		w.WriteHeader(http.StatusBadRequest)
+		fmt.Fprint(w, "bad request")
+	})
+	_, err = h.decomposeContent(context.Background(), "hi @alice", nil)
+	qt.Assert(t, err, qt.IsNotNil)
+}
+
+func TestComposeCastContent(t *testing.T) {
+	h := newTestHub(t, func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/userDataByFid":
+			fid := r.URL.Query().Get("fid")
+			writeJSON(w, &hubMessageResponse{Messages: []*hubMessage{{
+				Data: &hubMessageData{
+					Type:         MESSAGE_TYPE_USERDATA_ADD,
+					UserDataBody: &hubUserDataBody{Type: USERDATA_TYPE_USERNAME, Value: "user" + fid},
+				},
+			}}})
+		case "/userNameProofsByFid":
+			writeJSON(w, &custodyAddressResponse{})
+		case "/verificationsByFid":
+			writeJSON(w, &hubMessageResponse{})
+		default:
+			writeJSON(w, nil)
+		}
+	})
+	h.fid = 1
+
+	// the mention of the user at the beginning of a reply is not included,
+	// the rest are, even if they are repeated
+	body := &hubCastAddBody{
+		Text:              " hi ,  and !",
+		Mentions:          []uint64{1, 2, 3, 2, 4},
+		MentionsPositions: []uint64{0, 0, 4, 6, 11},
+	}
+	content, err := h.composeCastContent(context.Background(), body)
+	qt.Assert(t, err, qt.IsNil)
+	qt.Assert(t, content, qt.Equals, "@user2 hi @user3, @user2 and @user4!")
+
+	// more mentions than concurrent requests
+	body = &hubCastAddBody{}
+	expected := ""
+	for fid := uint64(10); fid < 10+3*maxConcurrentUserdataRequests; fid++ {
+		body.Text += " "
+		body.Mentions = append(body.Mentions, fid, fid)
+		body.MentionsPositions = append(body.MentionsPositions, uint64(len(body.Text)), uint64(len(body.Text)))
+		expected += fmt.Sprintf(" @user%d@user%d", fid, fid)
+	}
+	content, err = h.composeCastContent(context.Background(), body)
+	qt.Assert(t, err, qt.IsNil)
+	qt.Assert(t, content, qt.Equals, expected)
+
+	// the errors of the hub are returned
+	h = newTestHub(t, func(w http.ResponseWriter, _ *http.Request) {
+		w.WriteHeader(http.StatusBadRequest)
+		fmt.Fprint(w, "bad request")
+	})
+	_, err = h.composeCastContent(context.Background(), body)
+	qt.Assert(t, err, qt.IsNotNil)
+}
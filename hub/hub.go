@@ -1,19 +1,21 @@
 package hub
 
 import (
-	"bytes"
 	"context"
 	"crypto/ed25519"
 	"encoding/hex"
-	"encoding/json"
+	"errors"
 	"fmt"
-	"io"
 	"net/http"
+	"strconv"
 	"strings"
+	"sync"
 	"time"
 
 	hubproto "github.com/vocdoni/farcaster-go/hub/proto"
 	"go.vocdoni.io/dvote/log"
+	"golang.org/x/sync/singleflight"
+	"google.golang.org/protobuf/proto"
 )
 
 const (
@@ -26,48 +28,88 @@ const (
 	ENDPOINT_USER_FOLLOWERs        = "linksByTargetFid?target_fid=%d"
 	ENDPOINT_VERIFICATIONS         = "verificationsByFid?fid=%d"
 	ENDPOINT_IDREGISTRY_BY_ADDRESS = "onChainIdRegistryEventByAddress?address=%s"
+	ENDPOINT_EVENTS                = "events?from_event_id=%d"
+	ENDPOINT_CASTS_BY_PARENT       = "castsByParent?fid=%d&hash=%s"
+	ENDPOINT_CASTS_BY_FID          = "castsByFid?fid=%d"
+	ENDPOINT_REACTIONS_BY_CAST     = "reactionsByCast?target_fid=%d&target_hash=%s"
+	ENDPOINT_REACTIONS_BY_FID      = "reactionsByFid?fid=%d"
+	ENDPOINT_REACTIONS_BY_TARGET   = "reactionsByTarget?url=%s"
+	ENDPOINT_LINKS_BY_FID          = "linksByFid?fid=%d&link_type=follow"
+	ENDPOINT_LINK_BY_ID            = "linkById?fid=%d&target_fid=%d&link_type=follow"
+	ENDPOINT_CASTS_BY_PARENT_URL   = "castsByParent?url=%s"
+	ENDPOINT_USERNAME_PROOF        = "userNameProofByName?name=%s"
+	ENDPOINT_INFO                  = "info?dbstats=1"
+	ENDPOINT_ONCHAIN_EVENTS        = "onChainEventsByFid?fid=%d&event_type=%s"
+	ENDPOINT_VALIDATE_MESSAGE      = "validateMessage"
 	// timeouts
 	getCastTimeout          = 10 * time.Second
 	getCastByMentionTimeout = 15 * time.Second
 	submitMessageTimeout    = 5 * time.Minute
 	userdataTimeout         = 15 * time.Second
 	userFollowersTimeout    = 15 * time.Second
+	// maxInitialMentions is the maximum number of mentions returned by
+	// LastMentions when no timestamp is provided, to avoid walking the whole
+	// mention history of the user
+	maxInitialMentions = 100
+	// maxConcurrentUserdataRequests is the maximum number of user data
+	// requests performed concurrently to compose the content of a cast
+	maxConcurrentUserdataRequests = 10
 	// message types
-	MESSAGE_TYPE_CAST_ADD     = "MESSAGE_TYPE_CAST_ADD"
-	MESSAGE_TYPE_USERPROOF    = "USERNAME_TYPE_FNAME"
-	MESSAGE_TYPE_VERIFICATION = "MESSAGE_TYPE_VERIFICATION_ADD_ETH_ADDRESS"
-	MESSAGE_TYPE_LINK         = "MESSAGE_TYPE_LINK_ADD"
-	MESSAGE_TYPE_USERDATA_ADD = "MESSAGE_TYPE_USER_DATA_ADD"
+	MESSAGE_TYPE_CAST_ADD            = "MESSAGE_TYPE_CAST_ADD"
+	MESSAGE_TYPE_USERPROOF           = "USERNAME_TYPE_FNAME"
+	MESSAGE_TYPE_VERIFICATION        = "MESSAGE_TYPE_VERIFICATION_ADD_ETH_ADDRESS"
+	MESSAGE_TYPE_VERIFICATION_REMOVE = "MESSAGE_TYPE_VERIFICATION_REMOVE"
+	MESSAGE_TYPE_LINK                = "MESSAGE_TYPE_LINK_ADD"
+	MESSAGE_TYPE_LINK_REMOVE         = "MESSAGE_TYPE_LINK_REMOVE"
+	MESSAGE_TYPE_USERDATA_ADD        = "MESSAGE_TYPE_USER_DATA_ADD"
+	MESSAGE_TYPE_REACTION_ADD        = "MESSAGE_TYPE_REACTION_ADD"
 	// user data types
 	USERDATA_TYPE_USERNAME = "USER_DATA_TYPE_USERNAME"
+	USERDATA_TYPE_PFP      = "USER_DATA_TYPE_PFP"
+	USERDATA_TYPE_DISPLAY  = "USER_DATA_TYPE_DISPLAY"
+	USERDATA_TYPE_BIO      = "USER_DATA_TYPE_BIO"
+	USERDATA_TYPE_URL      = "USER_DATA_TYPE_URL"
+	// link types
+	LINK_TYPE_FOLLOW = "follow"
 	// other constants
 	farcasterEpoch uint64 = 1609459200 // January 1, 2021 UTC
 )
 
-// Hub struct implements the farcasterapi.API interface and represents the
-// API of a Farcaster Hub.
+// Hub struct implements the API interface and represents the API of a
+// Farcaster Hub.
 type Hub struct {
 	fid      uint64
-	signer   ed25519.PrivateKey
-	endpoint string
-	auth     map[string]string
+	signer   MessageSigner
+	network  hubproto.FarcasterNetwork
+	channels ChannelResolver
+	// hubs of the pool and its mutex
+	hubs    []*hubNode
+	hubsMtx sync.RWMutex
+	// user data cache and requests group
+	userdataCache UserdataCache
+	userdataGroup singleflight.Group
 }
 
-// Init initializes the API Hub with the given arguments.
+var _ API = (*Hub)(nil)
+
+// Init initializes the API Hub with the given arguments for the farcaster
+// mainnet.
 // ApiKeys must be a slice of strings with an even number of elements, where
 // each pair of elements is a header and a key. If let empty, not authentication
 // will be used.
 func NewHubAPI(hubApiEndpoint string, apiKeys []string) (*Hub, error) {
-	h := &Hub{endpoint: hubApiEndpoint}
-	// take the apikeys by group of two and set them as header/key
-	if len(apiKeys)%2 != 0 {
-		return nil, fmt.Errorf("invalid number of api keys")
-	}
-	h.auth = map[string]string{}
-	for i := 0; i < len(apiKeys); i += 2 {
-		h.auth[apiKeys[i]] = apiKeys[i+1]
-	}
-	return h, nil
+	return NewHubAPIWithNetwork(hubApiEndpoint, apiKeys, hubproto.FarcasterNetwork_FARCASTER_NETWORK_MAINNET)
+}
+
+// NewHubAPIWithNetwork initializes the API Hub with the given arguments for
+// the given farcaster network (mainnet, testnet or devnet). The messages built
+// by the API are intended for that network and the messages read from the hub
+// that are intended for other networks are discarded.
+// ApiKeys must be a slice of strings with an even number of elements, where
+// each pair of elements is a header and a key. If let empty, not authentication
+// will be used.
+func NewHubAPIWithNetwork(hubApiEndpoint string, apiKeys []string, network hubproto.FarcasterNetwork) (*Hub, error) {
+	return NewHubPool([]*HubEndpoint{{URL: hubApiEndpoint, APIKeys: apiKeys}}, network)
 }
 
 // SetFarcasterUser sets the farcaster user with the given fid and ed25519 hexadecimal signer.
@@ -76,7 +118,18 @@ func (h *Hub) SetFarcasterUser(fid uint64, signerPrivKey string) error {
 	if err != nil {
 		return fmt.Errorf("error decoding signer: %w", err)
 	}
-	h.signer = ed25519.NewKeyFromSeed(signerPrivKeyBytes)
+	h.signer = NewEd25519Signer(ed25519.NewKeyFromSeed(signerPrivKeyBytes))
+	h.fid = fid
+	return nil
+}
+
+// SetFarcasterSigner sets the farcaster user with the given fid and message
+// signer, to use a custom signer instead of an ed25519 hexadecimal key.
+func (h *Hub) SetFarcasterSigner(fid uint64, signer MessageSigner) error {
+	if signer == nil {
+		return fmt.Errorf("no signer provided")
+	}
+	h.signer = signer
 	h.fid = fid
 	return nil
 }
@@ -86,8 +139,48 @@ func (h *Hub) FID() uint64 {
 	return h.fid
 }
 
+// Network returns the farcaster network of the API.
+func (h *Hub) Network() hubproto.FarcasterNetwork {
+	return h.network
+}
+
+// VerifyMessage verifies the given message with VerifyMessage, checking that
+// it is intended for the network of the API. If isActiveSigner is not nil, it
+// is used to check that the signer is an active key of the message fid.
+func (h *Hub) VerifyMessage(msg *hubproto.Message, isActiveSigner SignerValidator) error {
+	return VerifyMessage(msg, h.network, isActiveSigner)
+}
+
+// ValidateMessage method asks the hub to validate the given message, checking
+// its hash, its signature and that its signer is an active key of the message
+// fid. It returns an error that matches ErrInvalidMessage if the hub
+// considers it not valid.
+func (h *Hub) ValidateMessage(ctx context.Context, msg *hubproto.Message) error {
+	msgBytes, err := proto.Marshal(msg)
+	if err != nil {
+		return fmt.Errorf("error marshalling message: %w", err)
+	}
+	// create a new context with a timeout
+	internalCtx, cancel := context.WithTimeout(ctx, submitMessageTimeout)
+	defer cancel()
+	res, err := h.do(internalCtx, http.MethodPost, ENDPOINT_VALIDATE_MESSAGE, msgBytes, "application/octet-stream")
+	if err != nil {
+		return fmt.Errorf("error validating the message: %w", err)
+	}
+	defer res.Body.Close()
+	validation := &hubValidateMessageResponse{}
+	if err := decodeJSONResponse(res, validation); err != nil {
+		return err
+	}
+	if !validation.Valid {
+		return fmt.Errorf("%w: rejected by the hub", ErrInvalidMessage)
+	}
+	return nil
+}
+
 // LastMentions method returns the last mentions for the configured user (with SetFarcasterUser).
-// It returns the messages, the last timestamp and an error.
+// It returns the messages, the last timestamp and an error. If the timestamp is
+// 0, only the newest mentions are returned, up to maxInitialMentions.
 func (h *Hub) LastMentions(ctx context.Context, timestamp uint64) ([]*APIMessage, uint64, error) {
 	if h.fid == 0 {
 		return nil, 0, fmt.Errorf("no farcaster user set")
@@ -97,72 +190,40 @@ func (h *Hub) LastMentions(ctx context.Context, timestamp uint64) ([]*APIMessage
 	}
 	internalCtx, cancel := context.WithTimeout(ctx, getCastByMentionTimeout)
 	defer cancel()
-	// download de json from API endpoint
-	uri := fmt.Sprintf(ENDPOINT_CAST_BY_MENTION, h.fid)
-	req, err := h.newRequest(internalCtx, http.MethodGet, uri, nil)
-	if err != nil {
-		return nil, 0, fmt.Errorf("error creating request: %w", err)
-	}
-	res, err := http.DefaultClient.Do(req)
-	if err != nil {
-		return nil, 0, fmt.Errorf("error downloading json: %w", err)
-	}
-	defer func() {
-		if err := res.Body.Close(); err != nil {
-			log.Error("error closing response body")
-		}
-	}()
-	if res.StatusCode != http.StatusOK {
-		return nil, 0, fmt.Errorf("error downloading json: %s", res.Status)
-	}
-	body, err := io.ReadAll(res.Body)
-	if err != nil {
-		return nil, 0, fmt.Errorf("error reading response body: %w", err)
-	}
-	// unmarshal the json
-	mentions := &hubMessageResponse{}
-	if err := json.Unmarshal(body, mentions); err != nil {
-		return nil, 0, fmt.Errorf("error unmarshalling json: %w", err)
-	}
-	// filter messages and calculate the last timestamp
+	// iterate over the mentions from the newest to the oldest, until the first
+	// mention older than the timestamp provided, and calculate the last
+	// timestamp
 	lastTimestamp := uint64(0)
 	messages := []*APIMessage{}
-	for _, m := range mentions.Messages {
+	opts := &PageOptions{Reverse: true}
+	if timestamp == 0 {
+		opts.PageSize = maxInitialMentions
+	}
+	uri := fmt.Sprintf(ENDPOINT_CAST_BY_MENTION, h.fid)
+	if _, err := h.iterateMessages(internalCtx, uri, opts, func(m *hubMessage) bool {
+		if m.Data.Timestamp <= timestamp {
+			return false
+		}
 		isMention := m.Data.Type == MESSAGE_TYPE_CAST_ADD && m.Data.CastAddBody != nil && m.Data.CastAddBody.Text != ""
 		if !isMention {
-			continue
+			return true
+		}
+		message, err := h.apiMessage(ctx, m)
+		if err != nil {
+			log.Warnw("invalid mention", "hash", m.HexHash, "error", err)
+			return true
 		}
-		if m.Data.Timestamp > timestamp {
-			content, err := h.composeCastContent(m.Data.CastAddBody)
-			if err != nil {
-				log.Error(err)
-			}
-			// parse the embeds of the message to be included
-			embeds := []string{}
-			if len(m.Data.CastAddBody.Embeds) > 0 {
-				for _, e := range m.Data.CastAddBody.Embeds {
-					embeds = append(embeds, e.Url)
-				}
-			}
-			var parent *ParentAPIMessage = nil
-			if m.Data.CastAddBody.ParentCast != nil {
-				parent = &ParentAPIMessage{
-					FID:  m.Data.CastAddBody.ParentCast.FID,
-					Hash: m.Data.CastAddBody.ParentCast.Hash,
-				}
-			}
-			messages = append(messages, &APIMessage{
-				IsMention: true,
-				Content:   content,
-				Author:    m.Data.From,
-				Hash:      m.HexHash,
-				Parent:    parent,
-				Embeds:    embeds,
-			})
-			if m.Data.Timestamp > lastTimestamp {
-				lastTimestamp = m.Data.Timestamp
-			}
+		message.IsMention = true
+		// prepend the message to keep them sorted from the oldest to the
+		// newest
+		messages = append([]*APIMessage{message}, messages...)
+		if m.Data.Timestamp > lastTimestamp {
+			lastTimestamp = m.Data.Timestamp
 		}
+		// without a timestamp, stop once the newest mentions are collected
+		return timestamp != 0 || len(messages) < maxInitialMentions
+	}); err != nil {
+		return nil, 0, fmt.Errorf("error downloading mentions: %w", err)
 	}
 	// if there are no new casts, return an error
 	if len(messages) == 0 {
@@ -172,72 +233,106 @@ func (h *Hub) LastMentions(ctx context.Context, timestamp uint64) ([]*APIMessage
 	return messages, lastTimestamp + farcasterEpoch, nil
 }
 
-// Cast receives a cast from the API with the given fid and hash.
-func (h *Hub) Cast(ctx context.Context, fid uint64, hash string) (*APIMessage, error) {
-	log.Infow("getting cast", "fid", fid, "hash", hash)
-	// create a new context with a timeout
-	internalCtx, cancel := context.WithTimeout(ctx, getCastTimeout)
-	defer cancel()
-	// compose endpoint uri
-	uri := fmt.Sprintf(ENDPOINT_GET_CAST, fid, hash)
-	// prepare the request to get the cast from the API
-	req, err := h.newRequest(internalCtx, http.MethodGet, uri, nil)
+// Mentions method returns the casts that mention the user with the given fid,
+// following the given page options. It returns the messages, the token to
+// request the next page (empty if there are no more pages) and an error.
+func (h *Hub) Mentions(ctx context.Context, fid uint64, opts *PageOptions) ([]*APIMessage, string, error) {
+	messages := []*APIMessage{}
+	uri := fmt.Sprintf(ENDPOINT_CAST_BY_MENTION, fid)
+	nextPageToken, err := h.iterateMessages(ctx, uri, opts, func(m *hubMessage) bool {
+		message, err := h.apiMessage(ctx, m)
+		if err != nil {
+			log.Warnw("invalid mention", "hash", m.HexHash, "error", err)
+			return true
+		}
+		message.IsMention = true
+		messages = append(messages, message)
+		return !opts.limitReached(len(messages))
+	})
 	if err != nil {
-		return nil, fmt.Errorf("error creating request: %s", err)
+		return nil, "", fmt.Errorf("error downloading mentions: %w", err)
 	}
-	// download the cast from the API and check for errors
-	res, err := http.DefaultClient.Do(req)
-	if err != nil {
-		return nil, fmt.Errorf("error downloading cast: %s", err)
+	return messages, nextPageToken, nil
+}
+
+// Casts method returns the casts published by the user with the given fid,
+// following the given options (if nil, every cast is returned from the oldest
+// to the newest). The content of the casts is composed with the mentions
+// usernames. It returns the messages, the token to request the next page
+// (empty if there are no more pages) and an error.
+func (h *Hub) Casts(ctx context.Context, fid uint64, opts *CastsOptions) ([]*APIMessage, string, error) {
+	if opts == nil {
+		opts = &CastsOptions{}
+	}
+	if opts.StartTimestamp > 0 && opts.EndTimestamp > 0 && opts.StartTimestamp > opts.EndTimestamp {
+		return nil, "", fmt.Errorf("invalid time range")
+	}
+	// convert the time range to farcaster timestamps and include it in the
+	// uri
+	var start, end uint64
+	uri := fmt.Sprintf(ENDPOINT_CASTS_BY_FID, fid)
+	if opts.StartTimestamp > 0 {
+		if opts.StartTimestamp > farcasterEpoch {
+			start = opts.StartTimestamp - farcasterEpoch
+		}
+		uri += fmt.Sprintf("&startTimestamp=%d", start)
 	}
-	defer res.Body.Close()
-	if res.StatusCode != http.StatusOK {
-		return nil, fmt.Errorf("error downloading cast: %s", res.Status)
+	if opts.EndTimestamp > 0 {
+		if opts.EndTimestamp < farcasterEpoch {
+			return []*APIMessage{}, "", nil
+		}
+		end = opts.EndTimestamp - farcasterEpoch
+		uri += fmt.Sprintf("&endTimestamp=%d", end)
 	}
-	// read the response body
-	body, err := io.ReadAll(res.Body)
+	messages := []*APIMessage{}
+	nextPageToken, err := h.iterateMessages(ctx, uri, &opts.PageOptions, func(m *hubMessage) bool {
+		// skip the casts out of the time range, in case the hub does not
+		// support filtering them
+		if (opts.StartTimestamp > 0 && m.Data.Timestamp < start) ||
+			(opts.EndTimestamp > 0 && m.Data.Timestamp > end) {
+			return true
+		}
+		message, err := h.apiMessage(ctx, m)
+		if err != nil {
+			log.Warnw("invalid cast", "hash", m.HexHash, "error", err)
+			return true
+		}
+		messages = append(messages, message)
+		return !opts.limitReached(len(messages))
+	})
 	if err != nil {
-		return nil, fmt.Errorf("error reading response body: %s", err)
-	}
-	// decode the cast from the body
-	msg := &hubMessage{}
-	if err := json.Unmarshal(body, msg); err != nil {
-		return nil, fmt.Errorf("error unmarshalling cast: %s", err)
-	}
-	// check if the message is a cast add
-	if msg.Data.Type != MESSAGE_TYPE_CAST_ADD || msg.Data.CastAddBody == nil {
-		return nil, fmt.Errorf("no valid cast")
+		return nil, "", fmt.Errorf("error downloading casts: %w", err)
 	}
-	// compose the content of the message
-	content, err := h.composeCastContent(msg.Data.CastAddBody)
+	return messages, nextPageToken, nil
+}
+
+// Cast receives a cast from the API with the given fid and hash.
+func (h *Hub) Cast(ctx context.Context, fid uint64, hash string) (*APIMessage, error) {
+	log.Infow("getting cast", "fid", fid, "hash", hash)
+	message, err := h.castByID(ctx, fid, hash)
 	if err != nil {
-		log.Error(err)
+		return nil, err
 	}
-	// parse the embeds of the message to be included
-	embeds := []string{}
-	if len(msg.Data.CastAddBody.Embeds) > 0 {
-		for _, e := range msg.Data.CastAddBody.Embeds {
-			embeds = append(embeds, e.Url)
-		}
-	}
-	// check if the message has a parent
-	var parent *ParentAPIMessage = nil
-	if msg.Data.CastAddBody.ParentCast != nil {
-		parent = &ParentAPIMessage{
-			FID:  msg.Data.CastAddBody.ParentCast.FID,
-			Hash: msg.Data.CastAddBody.ParentCast.Hash,
-		}
+	message.IsMention = true
+	return message, nil
+}
+
+// castByID method downloads the cast with the given fid and hash from the hub
+// and returns it as an APIMessage.
+func (h *Hub) castByID(ctx context.Context, fid uint64, hash string) (*APIMessage, error) {
+	// create a new context with a timeout
+	internalCtx, cancel := context.WithTimeout(ctx, getCastTimeout)
+	defer cancel()
+	// download the cast from the API and check for errors
+	msg := &hubMessage{}
+	if err := h.getJSON(internalCtx, fmt.Sprintf(ENDPOINT_GET_CAST, fid, hash), msg); err != nil {
+		return nil, fmt.Errorf("error downloading cast: %w", err)
 	}
 	// compose the api message
-	message := &APIMessage{
-		IsMention: true,
-		Content:   content,
-		Author:    msg.Data.From,
-		Hash:      msg.HexHash,
-		Parent:    parent,
-		Embeds:    embeds,
+	if err := h.checkNetwork(msg); err != nil {
+		return nil, err
 	}
-	return message, nil
+	return h.apiMessage(ctx, msg)
 }
 
 // Publish sends a new cast with the given content and embeds.
@@ -248,7 +343,7 @@ func (h *Hub) Publish(ctx context.Context, content string, mentionFIDs []uint64,
 		return fmt.Errorf("content is too long")
 	}
 	// create the cast add body
-	castBody, err := h.newAddCastBody(content, mentionFIDs, embeds...)
+	castBody, err := h.newAddCastBody(ctx, content, mentionFIDs, embeds...)
 	if err != nil {
 		return fmt.Errorf("error decomposing content: %s", err)
 	}
@@ -256,28 +351,43 @@ func (h *Hub) Publish(ctx context.Context, content string, mentionFIDs []uint64,
 	if err != nil {
 		return fmt.Errorf("error building and signing cast body: %s", err)
 	}
-	// create a new context with a timeout
-	internalCtx, cancel := context.WithTimeout(ctx, submitMessageTimeout)
-	defer cancel()
-	// submit the message to the API endpoint
-	req, err := h.newRequest(internalCtx, http.MethodPost, ENDPOINT_SUBMIT_MESSAGE, bytes.NewBuffer(msgBytes))
-	if err != nil {
-		return fmt.Errorf("error creating request: %s", err)
+	return h.submitMessage(ctx, msgBytes)
+}
+
+// RemoveCast method removes the cast with the given hash published by the
+// configured user (with SetFarcasterUser). It checks that the cast exists and
+// is owned by the configured user before submitting the remove message,
+// returning ErrCastNotOwned if it is not. If the hub rejects the message, it
+// returns a *HubError.
+func (h *Hub) RemoveCast(ctx context.Context, hash string) error {
+	log.Infow("removing cast", "hash", hash)
+	if h.fid == 0 {
+		return fmt.Errorf("no farcaster user set")
 	}
-	req.Header.Set("Content-Type", "application/octet-stream")
-	res, err := http.DefaultClient.Do(req)
+	bHash, err := decodeHash(hash)
 	if err != nil {
-		return fmt.Errorf("error submitting the message: %s", err)
+		return fmt.Errorf("error decoding cast hash: %w", err)
 	}
-	if res.StatusCode != http.StatusOK {
-		// read the response body
-		body, err := io.ReadAll(res.Body)
-		if err != nil {
-			return fmt.Errorf("error reading response body: %s", err)
+	// check that the cast is owned by the configured user, requesting the
+	// raw message to avoid composing its content
+	internalCtx, cancel := context.WithTimeout(ctx, getCastTimeout)
+	defer cancel()
+	if err := h.getJSON(internalCtx, fmt.Sprintf(ENDPOINT_GET_CAST, h.fid, hash), &hubMessage{}); err != nil {
+		if errors.Is(err, ErrNotFound) {
+			return ErrCastNotOwned
 		}
-		return fmt.Errorf("error submitting the message: %s", string(body))
+		return fmt.Errorf("error checking cast ownership: %w", err)
 	}
-	return nil
+	msgBytes, err := h.buildAndSignMessage(&hubproto.MessageData{
+		Type: hubproto.MessageType_MESSAGE_TYPE_CAST_REMOVE,
+		Body: &hubproto.MessageData_CastRemoveBody{
+			CastRemoveBody: &hubproto.CastRemoveBody{TargetHash: bHash},
+		},
+	})
+	if err != nil {
+		return fmt.Errorf("error building and signing cast remove: %w", err)
+	}
+	return h.submitMessage(ctx, msgBytes)
 }
 
 // Reply method sends a reply to the given targetFid and targetHash with the
@@ -293,13 +403,13 @@ func (h *Hub) Reply(ctx context.Context, targetMsg *APIMessage,
 	if len([]byte(content)) > MaxCastBytes {
 		return fmt.Errorf("content is too long")
 	}
-	castAdd, err := h.newAddCastBody(content, mentionFIDs, embeds...)
+	castAdd, err := h.newAddCastBody(ctx, content, mentionFIDs, embeds...)
 	if err != nil {
 		return fmt.Errorf("error creating cast add body: %s", err)
 	}
 	// create the cast as a reply to the message with the parentFID provided
 	// and the desired text
-	bTargetHash, err := hex.DecodeString(strings.TrimPrefix(targetMsg.Hash, "0x"))
+	bTargetHash, err := decodeHash(targetMsg.Hash)
 	if err != nil {
 		return fmt.Errorf("error decoding target hash: %s", err)
 	}
@@ -313,87 +423,98 @@ func (h *Hub) Reply(ctx context.Context, targetMsg *APIMessage,
 	if err != nil {
 		return fmt.Errorf("error building message: %s", err)
 	}
-	// create a new context with a timeout
-	internalCtx, cancel := context.WithTimeout(ctx, submitMessageTimeout)
-	defer cancel()
-	// submit the message to the API endpoint
-	req, err := h.newRequest(internalCtx, http.MethodPost, ENDPOINT_SUBMIT_MESSAGE, bytes.NewBuffer(msgBytes))
-	if err != nil {
-		return fmt.Errorf("error creating request: %s", err)
+	return h.submitMessage(ctx, msgBytes)
+}
+
+// Quote method sends a new cast with the given content and embeds that quotes
+// the cast with the given ID, embedding it in the new cast.
+func (h *Hub) Quote(ctx context.Context, quoted *CastID, content string,
+	mentionFIDs []uint64, embeds ...string,
+) error {
+	log.Infow("quoting cast", "quoted", quoted, "msg", content, "embeds", embeds)
+	if quoted == nil {
+		return fmt.Errorf("invalid quoted cast")
+	}
+	// check if the content is too long
+	if len([]byte(content)) > MaxCastBytes {
+		return fmt.Errorf("content is too long")
 	}
-	req.Header.Set("Content-Type", "application/octet-stream")
-	res, err := http.DefaultClient.Do(req)
+	castAdd, err := h.newAddCastBody(ctx, content, mentionFIDs, embeds...)
 	if err != nil {
-		return fmt.Errorf("error submitting the message: %s", err)
+		return fmt.Errorf("error creating cast add body: %w", err)
 	}
-	if res.StatusCode != http.StatusOK {
-		// read the response body
-		body, err := io.ReadAll(res.Body)
-		if err != nil {
-			return fmt.Errorf("error reading response body: %s", err)
-		}
-		return fmt.Errorf("error submitting the message: %s", string(body))
+	// include the quoted cast as the first embed of the cast
+	bQuotedHash, err := decodeHash(quoted.Hash)
+	if err != nil {
+		return fmt.Errorf("error decoding quoted hash: %w", err)
+	}
+	castAdd.Embeds = append([]*hubproto.Embed{{
+		Embed: &hubproto.Embed_CastId{
+			CastId: &hubproto.CastId{
+				Fid:  quoted.FID,
+				Hash: bQuotedHash,
+			},
+		},
+	}}, castAdd.Embeds...)
+	msgBytes, err := h.buildAndSignAddCastBody(castAdd)
+	if err != nil {
+		return fmt.Errorf("error building message: %w", err)
 	}
-	return nil
+	return h.submitMessage(ctx, msgBytes)
 }
 
 // UserDataByFID method returns the user data for the given FID. It includes the
 // username, the custody address, the verification addresses and the signers.
+// The user data is read from the cache of the API if it is available, and the
+// concurrent requests for the same FID are coalesced into a single one.
 func (h *Hub) UserDataByFID(ctx context.Context, fid uint64) (*Userdata, error) {
+	if h.userdataCache != nil {
+		if userdata, ok := h.userdataCache.Get(ctx, fid); ok {
+			return userdata.copy(), nil
+		}
+	}
+	// the shared request is not canceled if the context of the first caller
+	// is done, the rest of callers could still wait for it
+	resCh := h.userdataGroup.DoChan(strconv.FormatUint(fid, 10), func() (any, error) {
+		userdata, err := h.userDataByFID(context.WithoutCancel(ctx), fid)
+		if err != nil {
+			return nil, err
+		}
+		if h.userdataCache != nil {
+			h.userdataCache.Set(ctx, fid, userdata)
+		}
+		return userdata, nil
+	})
+	select {
+	case res := <-resCh:
+		if res.Err != nil {
+			return nil, res.Err
+		}
+		return res.Val.(*Userdata).copy(), nil
+	case <-ctx.Done():
+		return nil, ctx.Err()
+	}
+}
+
+// userDataByFID method downloads the user data for the given FID from the
+// hub, without using the cache.
+func (h *Hub) userDataByFID(ctx context.Context, fid uint64) (*Userdata, error) {
 	// create a intenal context with a timeout
 	internalCtx, cancel := context.WithTimeout(ctx, userdataTimeout)
 	defer cancel()
-	// prepare request to get the username
-	userdataReq, err := h.newRequest(internalCtx, http.MethodGet, fmt.Sprintf(ENDPOINT_USERDATA, fid), nil)
-	if err != nil {
-		return nil, fmt.Errorf("error creating user data request: %w", err)
-	}
-	// download the user data from the API and check for errors
-	userdataRes, err := http.DefaultClient.Do(userdataReq)
-	if err != nil {
+	// iterate over the user data messages to get the latest value of every
+	// user data type
+	profile := map[string]*UserdataField{}
+	if _, err := h.iterateMessages(internalCtx, fmt.Sprintf(ENDPOINT_USERDATA, fid), nil, func(msg *hubMessage) bool {
+		applyUserdataMessage(profile, msg)
+		return true
+	}); err != nil {
 		return nil, fmt.Errorf("error downloading user data: %w", err)
 	}
-	defer userdataRes.Body.Close()
-	userdataBody, err := io.ReadAll(userdataRes.Body)
-	if err != nil {
-		return nil, fmt.Errorf("error reading user data response body: %w", err)
-	}
-	userdata := &hubUserdataResponse{}
-	if err := json.Unmarshal(userdataBody, userdata); err != nil {
-		return nil, fmt.Errorf("error decoding user data: %w", err)
-	}
-	username := ""
-	lastUsername := uint64(0)
-	for _, msg := range userdata.Messages {
-		isUsername := msg.Data != nil && msg.Data.Type == MESSAGE_TYPE_USERDATA_ADD &&
-			msg.Data.Body != nil && msg.Data.Body.Type == USERDATA_TYPE_USERNAME
-		if isUsername && msg.Data.Timestamp > lastUsername {
-			username = msg.Data.Body.Value
-			lastUsername = msg.Data.Timestamp
-		}
-	}
-	// prepare the request to get the custody address from the API
-	custodyAddressReq, err := h.newRequest(internalCtx, http.MethodGet, fmt.Sprintf(ENDPOINT_CUSTODY_ADDRESS, fid), nil)
-	if err != nil {
-		return nil, fmt.Errorf("error creating custody address request: %w", err)
-	}
 	// download the custody address from the API and check for errors
-	custodyAddressRes, err := http.DefaultClient.Do(custodyAddressReq)
-	if err != nil {
-		return nil, fmt.Errorf("error downloading custody address: %w", err)
-	}
-	if custodyAddressRes.StatusCode != http.StatusOK {
-		return nil, fmt.Errorf("error downloading custody address: %s", custodyAddressRes.Status)
-	}
-	// read the response body
-	custodyAddressBody, err := io.ReadAll(custodyAddressRes.Body)
-	if err != nil {
-		return nil, fmt.Errorf("error reading user data response body: %w", err)
-	}
-	// unmarshal the json
 	custodyAddress := &custodyAddressResponse{}
-	if err := json.Unmarshal(custodyAddressBody, custodyAddress); err != nil {
-		return nil, fmt.Errorf("error unmarshalling user data: %w", err)
+	if err := h.getJSON(internalCtx, fmt.Sprintf(ENDPOINT_CUSTODY_ADDRESS, fid), custodyAddress); err != nil {
+		return nil, fmt.Errorf("error downloading custody address: %w", err)
 	}
 	// get the latest proof
 	lastProof := &usernameProofs{}
@@ -410,41 +531,25 @@ func (h *Hub) UserDataByFID(ctx context.Context, fid uint64) (*Userdata, error)
 			lastUserdataTimestamp = proof.Timestamp
 		}
 	}
-	// prepare the request to get verifications from the API
-	verificationsReq, err := h.newRequest(internalCtx, http.MethodGet, fmt.Sprintf(ENDPOINT_VERIFICATIONS, fid), nil)
-	if err != nil {
-		return nil, fmt.Errorf("error creating verifications request: %w", err)
-	}
-	// download the verifications from the API and check for errors
-	verificationsRes, err := http.DefaultClient.Do(verificationsReq)
-	if err != nil {
-		return nil, fmt.Errorf("error downloading verifications: %w", err)
-	}
-	if verificationsRes.StatusCode != http.StatusOK {
-		return nil, fmt.Errorf("error downloading verifications: %s", verificationsRes.Status)
-	}
-	// read the response body
-	verificationsBody, err := io.ReadAll(verificationsRes.Body)
+	// get the current verifications to split the EVM and Solana addresses
+	// and get the signers
+	verifications, err := h.Verifications(internalCtx, fid)
 	if err != nil {
-		return nil, fmt.Errorf("error reading verifications response body: %w", err)
+		return nil, err
 	}
-	// decode verifications json
-	verificationsData := &verificationsResponse{}
-	if err := json.Unmarshal(verificationsBody, verificationsData); err != nil {
-		return nil, fmt.Errorf("error unmarshalling verifications: %w", err)
-	}
-	// filter verifications addresses
-	verifications := []string{}
+	evmAddresses := []string{}
+	solanaAddresses := []string{}
 	signersMap := make(map[string]struct{})
-	for _, msg := range verificationsData.Messages {
-		// if no data or verification data is found, skip. If the message data
-		// type is not the one we are looking for, skip
-		if msg.Data == nil || msg.Data.Type != MESSAGE_TYPE_VERIFICATION || msg.Data.Verification == nil || msg.Data.Signer == "" {
-			log.Warnw("invalid verification message", "msg", msg)
-			continue
+	for _, verification := range verifications {
+		switch verification.Protocol {
+		case hubproto.Protocol_PROTOCOL_ETHEREUM:
+			evmAddresses = append(evmAddresses, verification.Address)
+		case hubproto.Protocol_PROTOCOL_SOLANA:
+			solanaAddresses = append(solanaAddresses, verification.Address)
+		}
+		if verification.Signer != "" {
+			signersMap[verification.Signer] = struct{}{}
 		}
-		verifications = append(verifications, msg.Data.Verification.Address)
-		signersMap[msg.Data.Signer] = struct{}{}
 	}
 	signers := []string{}
 	for signer := range signersMap {
@@ -452,10 +557,17 @@ func (h *Hub) UserDataByFID(ctx context.Context, fid uint64) (*Userdata, error)
 	}
 	return &Userdata{
 		FID:                    fid,
-		Username:               username,
+		Username:               profile[USERDATA_TYPE_USERNAME].value(),
+		Displayname:            profile[USERDATA_TYPE_DISPLAY].value(),
 		CustodyAddress:         lastProof.CustodyAddress,
-		VerificationsAddresses: verifications,
+		VerificationsAddresses: evmAddresses,
+		SolanaAddresses:        solanaAddresses,
+		Verifications:          verifications,
 		Signers:                signers,
+		Avatar:                 profile[USERDATA_TYPE_PFP].value(),
+		Bio:                    profile[USERDATA_TYPE_BIO].value(),
+		URL:                    profile[USERDATA_TYPE_URL].value(),
+		Profile:                profile,
 	}, nil
 }
 
@@ -465,38 +577,63 @@ func (h *Hub) UserFollowers(ctx context.Context, fid uint64) ([]uint64, error) {
 	// create a intenal context with a timeout
 	internalCtx, cancel := context.WithTimeout(ctx, userFollowersTimeout)
 	defer cancel()
-	// prepare the request to get the followers from the API
+	followersFids, _, err := h.Followers(internalCtx, fid, nil)
+	return followersFids, err
+}
+
+// Followers method returns the FIDs of the followers of the user with the
+// given id, following the given page options. The follow links are resolved
+// with last-write-wins per follower, so the removed follows are not included.
+// It returns the FIDs, the token to request the next page (empty if there are
+// no more pages) and an error.
+func (h *Hub) Followers(ctx context.Context, fid uint64, opts *PageOptions) ([]uint64, string, error) {
+	// filter the followers FIDs and return them
+	followers := newFollowSet()
 	uri := fmt.Sprintf(ENDPOINT_USER_FOLLOWERs, fid)
-	req, err := h.newRequest(internalCtx, http.MethodGet, uri, nil)
+	nextPageToken, err := h.iterateMessages(ctx, uri, opts, func(msg *hubMessage) bool {
+		followers.apply(msg.Data.From, msg)
+		return !opts.limitReached(followers.len())
+	})
 	if err != nil {
-		return nil, fmt.Errorf("error creating user followers request: %w", err)
+		return nil, "", fmt.Errorf("error downloading user followers: %w", err)
 	}
-	// download the followers from the API and check for errors
-	res, err := http.DefaultClient.Do(req)
-	if err != nil {
-		return nil, fmt.Errorf("error downloading user followers: %w", err)
-	}
-	defer res.Body.Close()
-	if res.StatusCode != http.StatusOK {
-		return nil, fmt.Errorf("error downloading user followers: %s", res.Status)
-	}
-	// read the response body
-	body, err := io.ReadAll(res.Body)
-	if err != nil {
-		return nil, fmt.Errorf("error reading user followers response body: %w", err)
-	}
-	// unmarshal the json
-	followersResponse := &hubMessageResponse{}
-	if err := json.Unmarshal(body, followersResponse); err != nil {
-		return nil, fmt.Errorf("error unmarshalling user followers: %w", err)
-	}
-	// filter the followers FIDs and return them
-	followersFids := []uint64{}
-	for _, msg := range followersResponse.Messages {
-		if msg.Data.Type != MESSAGE_TYPE_LINK {
-			continue
+	return followers.fids(), nextPageToken, nil
+}
+
+// Channel method returns the details of the channel with the given id using
+// the channel resolver of the API (with SetChannelResolver), because the
+// channels metadata is not part of the protocol. It returns ErrNotSupported if
+// no resolver is set.
+func (h *Hub) Channel(ctx context.Context, channelID string) (*Channel, error) {
+	if h.channels == nil {
+		return nil, ErrNotSupported
+	}
+	return h.channels.Channel(ctx, channelID)
+}
+
+// ChannelFIDs method returns the FIDs of the users that follow the channel
+// with the given id using the channel resolver of the API, if it supports it,
+// because the channel follows are not part of the protocol. Otherwise, it
+// returns ErrNotSupported.
+func (h *Hub) ChannelFIDs(ctx context.Context, channelID string, progress chan int) ([]uint64, error) {
+	resolver, ok := h.channels.(interface {
+		ChannelFIDs(context.Context, string, chan int) ([]uint64, error)
+	})
+	if !ok {
+		return nil, ErrNotSupported
+	}
+	return resolver.ChannelFIDs(ctx, channelID, progress)
+}
+
+// ChannelExists method returns if the channel with the given id exists using
+// the channel resolver of the API (with SetChannelResolver). It returns
+// ErrNotSupported if no resolver is set.
+func (h *Hub) ChannelExists(ctx context.Context, channelID string) (bool, error) {
+	if _, err := h.Channel(ctx, channelID); err != nil {
+		if errors.Is(err, ErrChannelNotFound) {
+			return false, nil
 		}
-		followersFids = append(followersFids, msg.Data.From)
+		return false, err
 	}
-	return followersFids, nil
+	return true, nil
 }
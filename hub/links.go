@@ -0,0 +1,164 @@
+package hub
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	hubproto "github.com/vocdoni/farcaster-go/hub/proto"
+	"go.vocdoni.io/dvote/log"
+)
+
+// Follow method follows the user with the given fid with the configured
+// farcaster user (with SetFarcasterUser). The displayTimestamp is an optional
+// unix timestamp that preserves the original time of the follow, if it is 0,
+// it is not included in the message.
+func (h *Hub) Follow(ctx context.Context, fid, displayTimestamp uint64) error {
+	return h.link(ctx, hubproto.MessageType_MESSAGE_TYPE_LINK_ADD, fid, displayTimestamp)
+}
+
+// Unfollow method unfollows the user with the given fid with the configured
+// farcaster user (with SetFarcasterUser). The displayTimestamp is an optional
+// unix timestamp that preserves the original time of the unfollow, if it is 0,
+// it is not included in the message.
+func (h *Hub) Unfollow(ctx context.Context, fid, displayTimestamp uint64) error {
+	return h.link(ctx, hubproto.MessageType_MESSAGE_TYPE_LINK_REMOVE, fid, displayTimestamp)
+}
+
+// Following method returns the FIDs of the users followed by the user with
+// the given id, following the given page options. The follow links are
+// resolved with last-write-wins per target, so the removed follows are not
+// included. It returns the FIDs, the token to request the next page (empty if
+// there are no more pages) and an error.
+func (h *Hub) Following(ctx context.Context, fid uint64, opts *PageOptions) ([]uint64, string, error) {
+	following := newFollowSet()
+	uri := fmt.Sprintf(ENDPOINT_LINKS_BY_FID, fid)
+	nextPageToken, err := h.iterateMessages(ctx, uri, opts, func(msg *hubMessage) bool {
+		if msg.Data.LinkBody != nil {
+			following.apply(msg.Data.LinkBody.TargetFID, msg)
+		}
+		return !opts.limitReached(following.len())
+	})
+	if err != nil {
+		return nil, "", fmt.Errorf("error downloading user following: %w", err)
+	}
+	return following.fids(), nextPageToken, nil
+}
+
+// Follows method returns if the user with the fid provided as follower
+// currently follows the user with the fid provided as target.
+func (h *Hub) Follows(ctx context.Context, follower, target uint64) (bool, error) {
+	// create a intenal context with a timeout
+	internalCtx, cancel := context.WithTimeout(ctx, userFollowersTimeout)
+	defer cancel()
+	msg := &hubMessage{}
+	if err := h.getJSON(internalCtx, fmt.Sprintf(ENDPOINT_LINK_BY_ID, follower, target), msg); err != nil {
+		if errors.Is(err, ErrNotFound) {
+			return false, nil
+		}
+		return false, fmt.Errorf("error downloading link: %w", err)
+	}
+	if err := h.checkNetwork(msg); err != nil {
+		return false, err
+	}
+	follows := newFollowSet()
+	if msg.Data != nil && msg.Data.LinkBody != nil {
+		follows.apply(msg.Data.LinkBody.TargetFID, msg)
+	}
+	return follows.len() > 0, nil
+}
+
+// followSet struct resolves the state of a set of follow links, applying
+// last-write-wins per FID: the link message with the highest timestamp
+// prevails and, if both have the same timestamp, the remove wins. It keeps
+// the order in which the FIDs were found.
+type followSet struct {
+	order    []uint64
+	states   map[uint64]*followState
+	followed int
+}
+
+type followState struct {
+	timestamp uint64
+	removed   bool
+}
+
+func newFollowSet() *followSet {
+	return &followSet{order: []uint64{}, states: map[uint64]*followState{}}
+}
+
+// apply method applies the given link message to the state of the given fid.
+// The messages that are not follow links are ignored.
+func (s *followSet) apply(fid uint64, msg *hubMessage) {
+	if msg.Data == nil || (msg.Data.Type != MESSAGE_TYPE_LINK && msg.Data.Type != MESSAGE_TYPE_LINK_REMOVE) ||
+		msg.Data.LinkBody == nil || msg.Data.LinkBody.Type != LINK_TYPE_FOLLOW {
+		return
+	}
+	removed := msg.Data.Type == MESSAGE_TYPE_LINK_REMOVE
+	state, ok := s.states[fid]
+	if !ok {
+		s.order = append(s.order, fid)
+		s.states[fid] = &followState{timestamp: msg.Data.Timestamp, removed: removed}
+		if !removed {
+			s.followed++
+		}
+		return
+	}
+	if msg.Data.Timestamp > state.timestamp || (msg.Data.Timestamp == state.timestamp && removed) {
+		if state.removed && !removed {
+			s.followed++
+		} else if !state.removed && removed {
+			s.followed--
+		}
+		state.timestamp = msg.Data.Timestamp
+		state.removed = removed
+	}
+}
+
+// len method returns the number of FIDs currently followed.
+func (s *followSet) len() int {
+	return s.followed
+}
+
+// fids method returns the FIDs currently followed.
+func (s *followSet) fids() []uint64 {
+	fids := []uint64{}
+	for _, fid := range s.order {
+		if !s.states[fid].removed {
+			fids = append(fids, fid)
+		}
+	}
+	return fids
+}
+
+// link method builds, signs and submits a follow link message of the given
+// message type (add or remove) to the given target fid. It returns an error if
+// the target or the display timestamp are not valid or something goes wrong
+// submitting the message.
+func (h *Hub) link(ctx context.Context, msgType hubproto.MessageType, targetFID, displayTimestamp uint64) error {
+	log.Infow("linking user", "type", msgType.String(), "target", targetFID)
+	if targetFID == 0 {
+		return fmt.Errorf("invalid target fid")
+	}
+	linkBody := &hubproto.LinkBody{
+		Type:   LINK_TYPE_FOLLOW,
+		Target: &hubproto.LinkBody_TargetFid{TargetFid: targetFID},
+	}
+	// include the display timestamp if it is provided, converted to the
+	// farcaster epoch
+	if displayTimestamp != 0 {
+		if displayTimestamp < farcasterEpoch {
+			return fmt.Errorf("invalid display timestamp")
+		}
+		fcTimestamp := uint32(displayTimestamp - farcasterEpoch)
+		linkBody.DisplayTimestamp = &fcTimestamp
+	}
+	msgBytes, err := h.buildAndSignMessage(&hubproto.MessageData{
+		Type: msgType,
+		Body: &hubproto.MessageData_LinkBody{LinkBody: linkBody},
+	})
+	if err != nil {
+		return fmt.Errorf("error building and signing link: %w", err)
+	}
+	return h.submitMessage(ctx, msgBytes)
+}
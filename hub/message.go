@@ -0,0 +1,231 @@
+package hub
+
+import (
+	"bytes"
+	"crypto/ed25519"
+	"fmt"
+	"time"
+
+	hubproto "github.com/vocdoni/farcaster-go/hub/proto"
+	"github.com/zeebo/blake3"
+	"google.golang.org/protobuf/proto"
+)
+
+// MessageHashLength is the length in bytes of the message hashes, which are
+// the first 20 bytes of the BLAKE3 digest of the message data (BLAKE3-160).
+const MessageHashLength = 20
+
+// MessageSigner interface defines the methods that a signer of farcaster
+// messages must implement to be used with BuildMessage.
+type MessageSigner interface {
+	// Scheme returns the signature scheme of the signer.
+	Scheme() hubproto.SignatureScheme
+	// PublicKey returns the public key (or address) of the signer, that is
+	// included in the signed messages.
+	PublicKey() []byte
+	// Sign returns the signature of the given message hash.
+	Sign(hash []byte) ([]byte, error)
+}
+
+// Ed25519Signer struct implements the MessageSigner interface using an
+// ed25519 private key, the default signature scheme of farcaster messages.
+type Ed25519Signer struct {
+	key ed25519.PrivateKey
+}
+
+// NewEd25519Signer creates a new Ed25519Signer with the given private key.
+func NewEd25519Signer(key ed25519.PrivateKey) *Ed25519Signer {
+	return &Ed25519Signer{key: key}
+}
+
+// Scheme method returns the ed25519 signature scheme.
+func (s *Ed25519Signer) Scheme() hubproto.SignatureScheme {
+	return hubproto.SignatureScheme_SIGNATURE_SCHEME_ED25519
+}
+
+// PublicKey method returns the ed25519 public key of the signer.
+func (s *Ed25519Signer) PublicKey() []byte {
+	return s.key.Public().(ed25519.PublicKey)
+}
+
+// Sign method signs the given message hash with the ed25519 private key.
+func (s *Ed25519Signer) Sign(hash []byte) ([]byte, error) {
+	return ed25519.Sign(s.key, hash), nil
+}
+
+// FarcasterTimestamp function returns the given time as a farcaster timestamp,
+// which is the number of seconds since the farcaster epoch.
+func FarcasterTimestamp(t time.Time) uint32 {
+	return uint32(uint64(t.Unix()) - farcasterEpoch)
+}
+
+// UnixTimestamp function returns the given farcaster timestamp as a unix
+// timestamp.
+func UnixTimestamp(timestamp uint32) uint64 {
+	return uint64(timestamp) + farcasterEpoch
+}
+
+// MessageHash function returns the BLAKE3-160 hash of the given serialized
+// message data.
+func MessageHash(dataBytes []byte) []byte {
+	hasher := blake3.New()
+	hasher.Write(dataBytes)
+	return hasher.Sum(nil)[:MessageHashLength]
+}
+
+// BuildMessage function builds and signs a farcaster message with the given
+// message data and signer. The message data must be complete (type, fid,
+// timestamp, network and a body that matches the type), this function does
+// not fill any field, so the same input always produces the same message. It
+// serializes the message data, calculates its BLAKE3-160 hash and signs it
+// with the signer. It returns the message and its serialized bytes, ready to
+// be submitted to a hub.
+func BuildMessage(data *hubproto.MessageData, signer MessageSigner) (*hubproto.Message, []byte, error) {
+	if signer == nil {
+		return nil, nil, fmt.Errorf("no signer provided")
+	}
+	if err := validateMessageData(data); err != nil {
+		return nil, nil, err
+	}
+	// marshal the message data
+	dataBytes, err := proto.Marshal(data)
+	if err != nil {
+		return nil, nil, fmt.Errorf("error marshalling message data: %w", err)
+	}
+	// calculate the hash of the message data and sign it
+	hash := MessageHash(dataBytes)
+	signature, err := signer.Sign(hash)
+	if err != nil {
+		return nil, nil, fmt.Errorf("error signing message: %w", err)
+	}
+	// compose the message with the hash, the signature and the signer
+	msg := &hubproto.Message{
+		Data:            data,
+		Hash:            hash,
+		HashScheme:      hubproto.HashScheme_HASH_SCHEME_BLAKE3,
+		Signature:       signature,
+		SignatureScheme: signer.Scheme(),
+		Signer:          signer.PublicKey(),
+		DataBytes:       dataBytes,
+	}
+	// marshal the message
+	msgBytes, err := proto.Marshal(msg)
+	if err != nil {
+		return nil, nil, fmt.Errorf("error marshalling message: %w", err)
+	}
+	return msg, msgBytes, nil
+}
+
+// validateMessageData function checks that the given message data includes
+// every required field and that its body matches its type. It returns an
+// error if it does not.
+func validateMessageData(data *hubproto.MessageData) error {
+	switch {
+	case data == nil:
+		return fmt.Errorf("no message data provided")
+	case data.Fid == 0:
+		return fmt.Errorf("no fid provided")
+	case data.Network == hubproto.FarcasterNetwork_FARCASTER_NETWORK_NONE:
+		return fmt.Errorf("no network provided")
+	}
+	var validBody bool
+	switch data.Type {
+	case hubproto.MessageType_MESSAGE_TYPE_CAST_ADD:
+		validBody = data.GetCastAddBody() != nil
+	case hubproto.MessageType_MESSAGE_TYPE_CAST_REMOVE:
+		validBody = data.GetCastRemoveBody() != nil
+	case hubproto.MessageType_MESSAGE_TYPE_REACTION_ADD, hubproto.MessageType_MESSAGE_TYPE_REACTION_REMOVE:
+		validBody = data.GetReactionBody() != nil
+	case hubproto.MessageType_MESSAGE_TYPE_LINK_ADD, hubproto.MessageType_MESSAGE_TYPE_LINK_REMOVE:
+		validBody = data.GetLinkBody() != nil
+	case hubproto.MessageType_MESSAGE_TYPE_VERIFICATION_ADD_ETH_ADDRESS:
+		validBody = data.GetVerificationAddAddressBody() != nil
+	case hubproto.MessageType_MESSAGE_TYPE_VERIFICATION_REMOVE:
+		validBody = data.GetVerificationRemoveBody() != nil
+	case hubproto.MessageType_MESSAGE_TYPE_USER_DATA_ADD:
+		validBody = data.GetUserDataBody() != nil
+	case hubproto.MessageType_MESSAGE_TYPE_USERNAME_PROOF:
+		validBody = data.GetUsernameProofBody() != nil
+	case hubproto.MessageType_MESSAGE_TYPE_FRAME_ACTION:
+		validBody = data.GetFrameActionBody() != nil
+	default:
+		return fmt.Errorf("invalid message type: %s", data.Type.String())
+	}
+	if !validBody {
+		return fmt.Errorf("message body does not match the message type %s", data.Type.String())
+	}
+	return nil
+}
+
+// SignerValidator is a function that returns if the given signer public key
+// is an active key of the given fid, for example checking the KeyRegistry
+// contract with web3.FarcasterProvider.SignersFromFID.
+type SignerValidator func(fid uint64, signer []byte) (bool, error)
+
+// VerifyMessage function verifies the given message offline. It recomputes
+// the BLAKE3-160 hash of the message data (from the data bytes if they are
+// included or from the serialized data otherwise) and checks it against the
+// message hash, checks the ed25519 signature of the hash and, if the network
+// provided is not FARCASTER_NETWORK_NONE, that the message is intended for
+// that network. If the message only includes the data bytes, the decoded data
+// is set in the message. If isActiveSigner is not nil, it is used to check
+// that the signer is an active key of the message fid. It returns an error
+// that matches ErrInvalidHashScheme, ErrInvalidHash,
+// ErrInvalidSignatureScheme, ErrInvalidSignature, ErrNetworkMismatch or
+// ErrInactiveSigner if the message is not valid.
+func VerifyMessage(msg *hubproto.Message, network hubproto.FarcasterNetwork, isActiveSigner SignerValidator) error {
+	if msg == nil {
+		return fmt.Errorf("no message provided")
+	}
+	if msg.HashScheme != hubproto.HashScheme_HASH_SCHEME_BLAKE3 {
+		return fmt.Errorf("%w: %s", ErrInvalidHashScheme, msg.HashScheme.String())
+	}
+	// get the serialized data to hash, from the data bytes if they are
+	// included or from the message data otherwise
+	dataBytes := msg.DataBytes
+	switch {
+	case len(dataBytes) > 0:
+		data := &hubproto.MessageData{}
+		if err := proto.Unmarshal(dataBytes, data); err != nil {
+			return fmt.Errorf("error decoding message data bytes: %w", err)
+		}
+		if msg.Data == nil {
+			msg.Data = data
+		} else if !proto.Equal(msg.Data, data) {
+			return fmt.Errorf("%w: message data does not match data bytes", ErrInvalidHash)
+		}
+	case msg.Data != nil:
+		var err error
+		if dataBytes, err = proto.Marshal(msg.Data); err != nil {
+			return fmt.Errorf("error marshalling message data: %w", err)
+		}
+	default:
+		return fmt.Errorf("no message data provided")
+	}
+	// check the hash
+	if !bytes.Equal(MessageHash(dataBytes), msg.Hash) {
+		return ErrInvalidHash
+	}
+	// check the signature
+	if msg.SignatureScheme != hubproto.SignatureScheme_SIGNATURE_SCHEME_ED25519 {
+		return fmt.Errorf("%w: %s", ErrInvalidSignatureScheme, msg.SignatureScheme.String())
+	}
+	if len(msg.Signer) != ed25519.PublicKeySize || !ed25519.Verify(msg.Signer, msg.Hash, msg.Signature) {
+		return ErrInvalidSignature
+	}
+	// check the network
+	if network != hubproto.FarcasterNetwork_FARCASTER_NETWORK_NONE && msg.Data.Network != network {
+		return fmt.Errorf("%w: %s (%s expected)", ErrNetworkMismatch, msg.Data.Network.String(), network.String())
+	}
+	// check the signer if a validator is provided
+	if isActiveSigner != nil {
+		active, err := isActiveSigner(msg.Data.Fid, msg.Signer)
+		if err != nil {
+			return fmt.Errorf("error checking signer: %w", err)
+		}
+		if !active {
+			return ErrInactiveSigner
+		}
+	}
+	return nil
+}
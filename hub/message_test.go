@@ -0,0 +1,128 @@
+package hub
+
+import (
+	"crypto/ed25519"
+	"encoding/hex"
+	"testing"
+
+	qt "github.com/frankban/quicktest"
+	hubproto "github.com/vocdoni/farcaster-go/hub/proto"
+	"github.com/zeebo/blake3"
+	"google.golang.org/protobuf/proto"
+)
+
+func TestBuildMessage(t *testing.T) {
+	q := qt.New(t)
+
+	// hardcoded ed25519 seed
+	seed, err := hex.DecodeString("1a2b3c4d5e6f708192a3b4c5d6e7f8091a2b3c4d5e6f708192a3b4c5d6e7f809")
+	q.Assert(err, qt.IsNil)
+	key := ed25519.NewKeyFromSeed(seed)
+	signer := NewEd25519Signer(key)
+
+	data := &hubproto.MessageData{
+		Type:      hubproto.MessageType_MESSAGE_TYPE_REACTION_ADD,
+		Fid:       529726,
+		Timestamp: 106150000,
+		Network:   hubproto.FarcasterNetwork_FARCASTER_NETWORK_MAINNET,
+		Body: &hubproto.MessageData_ReactionBody{
+			ReactionBody: &hubproto.ReactionBody{
+				Type:   hubproto.ReactionType_REACTION_TYPE_LIKE,
+				Target: &hubproto.ReactionBody_TargetUrl{TargetUrl: "https://vocdoni.io"},
+			},
+		},
+	}
+	msg, msgBytes, err := BuildMessage(data, signer)
+	q.Assert(err, qt.IsNil)
+
+	// the hash is the BLAKE3-160 of the serialized data
+	hasher := blake3.New()
+	hasher.Write(msg.DataBytes)
+	q.Assert(msg.Hash, qt.DeepEquals, hasher.Sum(nil)[:20])
+	q.Assert(msg.HashScheme, qt.Equals, hubproto.HashScheme_HASH_SCHEME_BLAKE3)
+
+	// the signature is a valid ed25519 signature of the hash
+	q.Assert(msg.SignatureScheme, qt.Equals, hubproto.SignatureScheme_SIGNATURE_SCHEME_ED25519)
+	q.Assert([]byte(msg.Signer), qt.DeepEquals, []byte(key.Public().(ed25519.PublicKey)))
+	q.Assert(ed25519.Verify(msg.Signer, msg.Hash, msg.Signature), qt.IsTrue)
+
+	// the wire bytes decode to the same message
+	decoded := &hubproto.Message{}
+	q.Assert(proto.Unmarshal(msgBytes, decoded), qt.IsNil)
+	q.Assert(proto.Equal(decoded, msg), qt.IsTrue)
+
+	// the wire bytes match a known vector, generated outside of this package
+	// for the same seed, fid, timestamp and body
+	q.Assert(hex.EncodeToString(msg.Hash), qt.Equals, "70de9d9ca9219b135cd07a8a3975fad35fe3a85c")
+	q.Assert(hex.EncodeToString(msg.Signature), qt.Equals,
+		"40838951db07073e4da1a2538e4f6faea884c492b8fb48184ebc6b6437a15428"+
+			"22b6648b1f779912b30f293039814251266278a70ca5a0627a03821515150502")
+	expected, err := hex.DecodeString(
+		"0a25080310beaa2018f0f0ce3220013a1608011a1268747470733a2f2f766f63646f6e692e696f" +
+			"121470de9d9ca9219b135cd07a8a3975fad35fe3a85c1801" +
+			"224040838951db07073e4da1a2538e4f6faea884c492b8fb48184ebc6b6437a15428" +
+			"22b6648b1f779912b30f293039814251266278a70ca5a0627a038215151505022801" +
+			"3220069aba87747dd9c1f46f24004eb79e05eb8f2e0f2c3adfdde60c1e2e00bac838" +
+			"3a25080310beaa2018f0f0ce3220013a1608011a1268747470733a2f2f766f63646f6e692e696f")
+	q.Assert(err, qt.IsNil)
+	q.Assert(msgBytes, qt.DeepEquals, expected)
+
+	// the builder is pure, the same input produces the same output
+	_, msgBytes2, err := BuildMessage(data, signer)
+	q.Assert(err, qt.IsNil)
+	q.Assert(msgBytes2, qt.DeepEquals, msgBytes)
+
+	// the body must match the message type
+	data.Type = hubproto.MessageType_MESSAGE_TYPE_CAST_ADD
+	_, _, err = BuildMessage(data, signer)
+	q.Assert(err, qt.IsNotNil)
+}
+
+func TestVerifyMessage(t *testing.T) {
+	q := qt.New(t)
+
+	_, key, err := ed25519.GenerateKey(nil)
+	q.Assert(err, qt.IsNil)
+	data := &hubproto.MessageData{
+		Type:      hubproto.MessageType_MESSAGE_TYPE_CAST_REMOVE,
+		Fid:       529726,
+		Timestamp: 106150000,
+		Network:   hubproto.FarcasterNetwork_FARCASTER_NETWORK_MAINNET,
+		Body: &hubproto.MessageData_CastRemoveBody{
+			CastRemoveBody: &hubproto.CastRemoveBody{TargetHash: make([]byte, MessageHashLength)},
+		},
+	}
+	_, msgBytes, err := BuildMessage(data, NewEd25519Signer(key))
+	q.Assert(err, qt.IsNil)
+	// decode a fresh copy of the message for every case
+	decode := func() *hubproto.Message {
+		msg := &hubproto.Message{}
+		q.Assert(proto.Unmarshal(msgBytes, msg), qt.IsNil)
+		return msg
+	}
+
+	// valid message, with and without data bytes
+	q.Assert(VerifyMessage(decode(), hubproto.FarcasterNetwork_FARCASTER_NETWORK_MAINNET, nil), qt.IsNil)
+	msg := decode()
+	msg.DataBytes = nil
+	q.Assert(VerifyMessage(msg, hubproto.FarcasterNetwork_FARCASTER_NETWORK_MAINNET, nil), qt.IsNil)
+	// only data bytes, the data is decoded
+	msg = decode()
+	msg.Data = nil
+	q.Assert(VerifyMessage(msg, hubproto.FarcasterNetwork_FARCASTER_NETWORK_NONE, nil), qt.IsNil)
+	q.Assert(msg.Data.Fid, qt.Equals, uint64(529726))
+	// tampered data
+	msg = decode()
+	msg.DataBytes = nil
+	msg.Data.Fid = 1
+	q.Assert(VerifyMessage(msg, hubproto.FarcasterNetwork_FARCASTER_NETWORK_NONE, nil), qt.ErrorIs, ErrInvalidHash)
+	// tampered signature
+	msg = decode()
+	msg.Signature[0] ^= 0xff
+	q.Assert(VerifyMessage(msg, hubproto.FarcasterNetwork_FARCASTER_NETWORK_NONE, nil), qt.ErrorIs, ErrInvalidSignature)
+	// wrong network
+	q.Assert(VerifyMessage(decode(), hubproto.FarcasterNetwork_FARCASTER_NETWORK_TESTNET, nil), qt.ErrorIs, ErrNetworkMismatch)
+	// inactive signer
+	inactive := func(uint64, []byte) (bool, error) { return false, nil }
+	q.Assert(VerifyMessage(decode(), hubproto.FarcasterNetwork_FARCASTER_NETWORK_NONE, inactive), qt.ErrorIs, ErrInactiveSigner)
+}
@@ -0,0 +1,159 @@
+package hub
+
+import (
+	"context"
+	"fmt"
+	"net/url"
+	"strings"
+
+	"github.com/ethereum/go-ethereum/common"
+)
+
+// OnChainEventType is the type of the on-chain events indexed by the hub.
+type OnChainEventType string
+
+const (
+	// OnChainEventSigner is emitted when a signer is added or removed in the
+	// key registry.
+	OnChainEventSigner OnChainEventType = "EVENT_TYPE_SIGNER"
+	// OnChainEventSignerMigrated is emitted when the signers are migrated to
+	// a new key registry.
+	OnChainEventSignerMigrated OnChainEventType = "EVENT_TYPE_SIGNER_MIGRATED"
+	// OnChainEventIDRegister is emitted when a fid is registered, transferred
+	// or its recovery address changes in the id registry. The kind of event is
+	// defined by the EventType of its IDRegisterEventBody.
+	OnChainEventIDRegister OnChainEventType = "EVENT_TYPE_ID_REGISTER"
+	// OnChainEventStorageRent is emitted when storage units are rented for a
+	// fid in the storage registry.
+	OnChainEventStorageRent OnChainEventType = "EVENT_TYPE_STORAGE_RENT"
+
+	// IDRegisterEventRegister is the id register event type of the fid
+	// registrations.
+	IDRegisterEventRegister = "ID_REGISTER_EVENT_TYPE_REGISTER"
+	// IDRegisterEventTransfer is the id register event type of the fid
+	// transfers.
+	IDRegisterEventTransfer = "ID_REGISTER_EVENT_TYPE_TRANSFER"
+	// IDRegisterEventChangeRecovery is the id register event type of the
+	// changes of the recovery address.
+	IDRegisterEventChangeRecovery = "ID_REGISTER_EVENT_TYPE_CHANGE_RECOVERY"
+)
+
+// OnChainEvent struct represents an event of the farcaster contracts indexed
+// by the hub. Only the body of its type is set. The block timestamp is a unix
+// timestamp.
+type OnChainEvent struct {
+	Type            OnChainEventType      `json:"type"`
+	ChainID         uint32                `json:"chainId"`
+	BlockNumber     uint64                `json:"blockNumber"`
+	BlockHash       string                `json:"blockHash"`
+	BlockTimestamp  uint64                `json:"blockTimestamp"`
+	TransactionHash string                `json:"transactionHash"`
+	LogIndex        uint32                `json:"logIndex"`
+	FID             uint64                `json:"fid"`
+	Signer          *SignerEventBody      `json:"signerEventBody,omitempty"`
+	IDRegister      *IDRegisterEventBody  `json:"idRegisterEventBody,omitempty"`
+	StorageRent     *StorageRentEventBody `json:"storageRentEventBody,omitempty"`
+}
+
+// SignerEventBody struct contains the details of a signer event.
+type SignerEventBody struct {
+	Key          string `json:"key"`
+	KeyType      uint32 `json:"keyType"`
+	EventType    string `json:"eventType"`
+	Metadata     string `json:"metadata"`
+	MetadataType uint32 `json:"metadataType"`
+}
+
+// IDRegisterEventBody struct contains the details of an id register event,
+// the custody addresses involved and the kind of event (register, transfer or
+// change of recovery address).
+type IDRegisterEventBody struct {
+	To              string `json:"to"`
+	From            string `json:"from"`
+	EventType       string `json:"eventType"`
+	RecoveryAddress string `json:"recoveryAddress"`
+}
+
+// StorageRentEventBody struct contains the details of a storage rent event.
+type StorageRentEventBody struct {
+	Payer  string `json:"payer"`
+	Units  uint32 `json:"units"`
+	Expiry uint32 `json:"expiry"`
+}
+
+// FIDByCustodyAddress method returns the fid owned by the given custody
+// address using the id registry events indexed by the hub. It returns an
+// error that matches ErrNotFound if the address does not own any fid.
+func (h *Hub) FIDByCustodyAddress(ctx context.Context, address string) (uint64, error) {
+	if !common.IsHexAddress(address) {
+		return 0, fmt.Errorf("invalid custody address: %s", address)
+	}
+	// normalize the address, it could be provided without the 0x prefix
+	custody := common.HexToAddress(address).Hex()
+	// create a intenal context with a timeout
+	internalCtx, cancel := context.WithTimeout(ctx, userdataTimeout)
+	defer cancel()
+	event := &OnChainEvent{}
+	uri := fmt.Sprintf(ENDPOINT_IDREGISTRY_BY_ADDRESS, strings.ToLower(custody))
+	if err := h.getJSON(internalCtx, uri, event); err != nil {
+		return 0, fmt.Errorf("error downloading id registry event: %w", err)
+	}
+	// the last event of the address must be the one that transferred the fid
+	// to it
+	if event.Type != OnChainEventIDRegister || event.IDRegister == nil || event.FID == 0 ||
+		!strings.EqualFold(event.IDRegister.To, custody) {
+		return 0, fmt.Errorf("%w: no fid owned by %s", ErrNotFound, address)
+	}
+	return event.FID, nil
+}
+
+// OnChainEvents method returns the on-chain events of the given type of the
+// user with the given fid, following the given page options. It returns the
+// events, the token to request the next page (empty if there are no more
+// pages) and an error.
+func (h *Hub) OnChainEvents(ctx context.Context, fid uint64, eventType OnChainEventType,
+	opts *PageOptions,
+) ([]*OnChainEvent, string, error) {
+	switch eventType {
+	case OnChainEventSigner, OnChainEventSignerMigrated, OnChainEventIDRegister, OnChainEventStorageRent:
+	default:
+		return nil, "", fmt.Errorf("unsupported on-chain event type: %s", eventType)
+	}
+	if opts == nil {
+		opts = &PageOptions{}
+	}
+	uri := fmt.Sprintf(ENDPOINT_ONCHAIN_EVENTS, fid, eventType)
+	if opts.PageSize > 0 {
+		uri += fmt.Sprintf("&pageSize=%d", opts.PageSize)
+	}
+	if opts.Reverse {
+		uri += "&reverse=true"
+	}
+	events := []*OnChainEvent{}
+	pageToken := opts.PageToken
+	for {
+		pageURI := uri
+		if pageToken != "" {
+			pageURI += "&pageToken=" + url.QueryEscape(pageToken)
+		}
+		res := &hubOnChainEventsResponse{}
+		if err := h.getJSON(ctx, pageURI, res); err != nil {
+			return nil, "", fmt.Errorf("error downloading on-chain events: %w", err)
+		}
+		for i, event := range res.Events {
+			events = append(events, event)
+			if opts.limitReached(len(events)) {
+				// if it stops in the last event of the page, the listing can
+				// be resumed from the next page
+				if i == len(res.Events)-1 {
+					return events, res.NextPageToken, nil
+				}
+				return events, pageToken, nil
+			}
+		}
+		if res.NextPageToken == "" {
+			return events, "", nil
+		}
+		pageToken = res.NextPageToken
+	}
+}
@@ -0,0 +1,38 @@
+package hub
+
+import (
+	"context"
+	"net/http"
+	"strings"
+	"testing"
+
+	qt "github.com/frankban/quicktest"
+)
+
+func TestFIDByCustodyAddress(t *testing.T) {
+	c := qt.New(t)
+
+	h := newTestHub(t, func(w http.ResponseWriter, r *http.Request) {
+		address := r.URL.Query().Get("address")
+		if r.URL.Path != "/onChainIdRegistryEventByAddress" || address != strings.ToLower(testEthAddress) {
+			writeJSON(w, nil)
+			return
+		}
+		writeJSON(w, &OnChainEvent{
+			Type:       OnChainEventIDRegister,
+			FID:        529726,
+			IDRegister: &IDRegisterEventBody{To: address, EventType: IDRegisterEventTransfer},
+		})
+	})
+
+	// the address is accepted in any case and without the 0x prefix
+	for _, address := range []string{testEthAddress, strings.ToLower(testEthAddress), strings.TrimPrefix(testEthAddress, "0x")} {
+		fid, err := h.FIDByCustodyAddress(context.Background(), address)
+		c.Assert(err, qt.IsNil, qt.Commentf("address: %s", address))
+		c.Assert(fid, qt.Equals, uint64(529726))
+	}
+	_, err := h.FIDByCustodyAddress(context.Background(), "0x0000000000000000000000000000000000000001")
+	c.Assert(err, qt.ErrorIs, ErrNotFound)
+	_, err = h.FIDByCustodyAddress(context.Background(), "0x01")
+	c.Assert(err, qt.IsNotNil)
+}
@@ -0,0 +1,292 @@
+package hub
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"sort"
+	"sync"
+	"time"
+
+	hubproto "github.com/vocdoni/farcaster-go/hub/proto"
+	"go.vocdoni.io/dvote/log"
+)
+
+const (
+	// healthCheckTimeout is the timeout of the info request of every hub
+	healthCheckTimeout = 10 * time.Second
+	// defaultHealthCheckInterval is the interval of the background health
+	// checks if no valid interval is provided
+	defaultHealthCheckInterval = time.Minute
+	// maxSyncLag is the maximum fraction of messages that a hub can be behind
+	// the most synced hub of the pool to be considered healthy
+	maxSyncLag = 0.01
+)
+
+// HubEndpoint struct defines a hub of a pool, its URL and the api keys to
+// authenticate the requests to it. The APIKeys must be a slice of strings
+// with an even number of elements, where each pair of elements is a header
+// and a key.
+type HubEndpoint struct {
+	URL     string
+	APIKeys []string
+}
+
+// HubInfo struct contains the information returned by the info endpoint of a
+// hub.
+type HubInfo struct {
+	Version     string `json:"version"`
+	IsSyncing   bool   `json:"isSyncing"`
+	Nickname    string `json:"nickname"`
+	RootHash    string `json:"rootHash"`
+	PeerID      string `json:"peerId"`
+	OperatorFID uint64 `json:"hubOperatorFid"`
+	DBStats     struct {
+		NumMessages    uint64 `json:"numMessages"`
+		NumFidEvents   uint64 `json:"numFidEvents"`
+		NumFnameEvents uint64 `json:"numFnameEvents"`
+	} `json:"dbStats"`
+}
+
+// HubStatus struct contains the health status of a hub of the pool after the
+// last health check. If the hub has not been checked yet, it is considered
+// healthy.
+type HubStatus struct {
+	URL       string
+	Healthy   bool
+	Info      *HubInfo
+	LastCheck time.Time
+	Error     error
+}
+
+// hubNode struct represents a hub of the pool with its current status.
+type hubNode struct {
+	endpoint string
+	auth     map[string]string
+	status   HubStatus
+}
+
+// NewHubPool initializes the API Hub with a pool of hubs for the given
+// farcaster network (mainnet, testnet or devnet). The reads are routed to the
+// healthy hubs of the pool, sorted by their sync state, and the submits fail
+// over to the next hub if one is not available. The hubs health is updated
+// with CheckHealth or periodically with StartHealthChecks.
+func NewHubPool(endpoints []*HubEndpoint, network hubproto.FarcasterNetwork) (*Hub, error) {
+	if _, ok := hubproto.FarcasterNetwork_name[int32(network)]; !ok ||
+		network == hubproto.FarcasterNetwork_FARCASTER_NETWORK_NONE {
+		return nil, fmt.Errorf("invalid network: %d", network)
+	}
+	if len(endpoints) == 0 {
+		return nil, fmt.Errorf("no hub endpoints provided")
+	}
+	h := &Hub{
+		network:       network,
+		userdataCache: NewLRUCache(DefaultUserdataCacheSize, DefaultUserdataCacheTTL),
+	}
+	for _, endpoint := range endpoints {
+		if endpoint == nil || endpoint.URL == "" {
+			return nil, fmt.Errorf("invalid hub endpoint")
+		}
+		// take the apikeys by group of two and set them as header/key
+		if len(endpoint.APIKeys)%2 != 0 {
+			return nil, fmt.Errorf("invalid number of api keys")
+		}
+		node := &hubNode{
+			endpoint: endpoint.URL,
+			auth:     map[string]string{},
+			status:   HubStatus{URL: endpoint.URL, Healthy: true},
+		}
+		for i := 0; i < len(endpoint.APIKeys); i += 2 {
+			node.auth[endpoint.APIKeys[i]] = endpoint.APIKeys[i+1]
+		}
+		h.hubs = append(h.hubs, node)
+	}
+	return h, nil
+}
+
+// Status method returns the current status of the hubs of the pool.
+func (h *Hub) Status() []*HubStatus {
+	h.hubsMtx.RLock()
+	defer h.hubsMtx.RUnlock()
+	statuses := []*HubStatus{}
+	for _, node := range h.hubs {
+		status := node.status
+		statuses = append(statuses, &status)
+	}
+	return statuses
+}
+
+// CheckHealth method checks the health and the sync state of every hub of the
+// pool through their info endpoint. A hub is healthy if it responds, it is not
+// syncing and it is not behind the most synced hub of the pool. It returns
+// the updated status of the hubs.
+func (h *Hub) CheckHealth(ctx context.Context) []*HubStatus {
+	h.hubsMtx.RLock()
+	nodes := append([]*hubNode{}, h.hubs...)
+	h.hubsMtx.RUnlock()
+	// request the info of every hub concurrently
+	statuses := make([]HubStatus, len(nodes))
+	wg := sync.WaitGroup{}
+	for i, node := range nodes {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			statuses[i] = HubStatus{URL: node.endpoint, LastCheck: time.Now()}
+			internalCtx, cancel := context.WithTimeout(ctx, healthCheckTimeout)
+			defer cancel()
+			info := &HubInfo{}
+			if err := h.getNodeJSON(internalCtx, node, ENDPOINT_INFO, info); err != nil {
+				statuses[i].Error = err
+				return
+			}
+			statuses[i].Info = info
+			if info.IsSyncing {
+				statuses[i].Error = fmt.Errorf("hub is syncing")
+			}
+		}()
+	}
+	wg.Wait()
+	// the hubs that are behind the most synced one are not healthy
+	maxMessages := uint64(0)
+	for _, status := range statuses {
+		if status.Info != nil && status.Info.DBStats.NumMessages > maxMessages {
+			maxMessages = status.Info.DBStats.NumMessages
+		}
+	}
+	for i := range statuses {
+		if statuses[i].Error == nil && statuses[i].Info != nil &&
+			float64(statuses[i].Info.DBStats.NumMessages) < float64(maxMessages)*(1-maxSyncLag) {
+			statuses[i].Error = fmt.Errorf("hub is behind in sync")
+		}
+		statuses[i].Healthy = statuses[i].Error == nil
+		if !statuses[i].Healthy {
+			log.Warnw("unhealthy hub", "url", statuses[i].URL, "error", statuses[i].Error)
+		}
+	}
+	h.hubsMtx.Lock()
+	for i, node := range nodes {
+		node.status = statuses[i]
+	}
+	h.hubsMtx.Unlock()
+	return h.Status()
+}
+
+// StartHealthChecks method checks the health of the hubs of the pool every
+// given interval in background, until the given context is done. If the
+// interval is not positive, defaultHealthCheckInterval is used.
+func (h *Hub) StartHealthChecks(ctx context.Context, interval time.Duration) {
+	if interval <= 0 {
+		interval = defaultHealthCheckInterval
+	}
+	go func() {
+		ticker := time.NewTicker(interval)
+		defer ticker.Stop()
+		for {
+			h.CheckHealth(ctx)
+			select {
+			case <-ticker.C:
+			case <-ctx.Done():
+				return
+			}
+		}
+	}()
+}
+
+// nodesByPriority method returns the hubs of the pool in the order that they
+// should be requested: first the healthy ones, sorted by the number of
+// messages they have, and then the unhealthy ones as last resort.
+func (h *Hub) nodesByPriority() []*hubNode {
+	h.hubsMtx.RLock()
+	defer h.hubsMtx.RUnlock()
+	nodes := append([]*hubNode{}, h.hubs...)
+	messages := func(n *hubNode) uint64 {
+		if n.status.Info == nil {
+			return 0
+		}
+		return n.status.Info.DBStats.NumMessages
+	}
+	sort.SliceStable(nodes, func(i, j int) bool {
+		if nodes[i].status.Healthy != nodes[j].status.Healthy {
+			return nodes[i].status.Healthy
+		}
+		return messages(nodes[i]) > messages(nodes[j])
+	})
+	return nodes
+}
+
+// do method performs a request with the given method, uri and body to the
+// hubs of the pool, by priority, until one of them responds. If a hub is not
+// reachable or it responds with a server error, the next one is requested.
+// The rest of responses (including client errors) are returned to the caller,
+// that must close the response body.
+func (h *Hub) do(ctx context.Context, method, uri string, body []byte, contentType string) (*http.Response, error) {
+	var lastErr error
+	for _, node := range h.nodesByPriority() {
+		var reqBody io.Reader
+		if body != nil {
+			reqBody = bytes.NewReader(body)
+		}
+		req, err := node.newRequest(ctx, method, uri, reqBody)
+		if err != nil {
+			return nil, err
+		}
+		if contentType != "" {
+			req.Header.Set("Content-Type", contentType)
+		}
+		res, err := http.DefaultClient.Do(req)
+		if err != nil {
+			// if the context is done, do not try with the rest of hubs
+			if ctxErr := ctx.Err(); ctxErr != nil {
+				return nil, errors.Join(err, ctxErr)
+			}
+			log.Warnw("hub request failed, trying next hub", "url", node.endpoint, "error", err)
+			lastErr = err
+			continue
+		}
+		if res.StatusCode >= http.StatusInternalServerError || res.StatusCode == http.StatusTooManyRequests {
+			hubErr := newHubError(res)
+			res.Body.Close()
+			log.Warnw("hub request failed, trying next hub", "url", node.endpoint, "error", hubErr)
+			lastErr = hubErr
+			continue
+		}
+		return res, nil
+	}
+	return nil, lastErr
+}
+
+// getNodeJSON method performs a GET request to the given uri of the given hub
+// and decodes the JSON response into the given value. If the hub responds with
+// an error, it returns a *HubError.
+func (h *Hub) getNodeJSON(ctx context.Context, node *hubNode, uri string, value any) error {
+	req, err := node.newRequest(ctx, http.MethodGet, uri, nil)
+	if err != nil {
+		return err
+	}
+	res, err := http.DefaultClient.Do(req)
+	if err != nil {
+		return fmt.Errorf("error downloading json: %w", err)
+	}
+	defer res.Body.Close()
+	return decodeJSONResponse(res, value)
+}
+
+// newRequest method creates a new http request to the hub with the given
+// method, uri and body. It returns the request and an error.
+func (n *hubNode) newRequest(ctx context.Context, method string, uri string, body io.Reader) (*http.Request, error) {
+	endpoint := fmt.Sprintf("%s/%s", n.endpoint, uri)
+	req, err := http.NewRequestWithContext(ctx, method, endpoint, body)
+	if err != nil {
+		return nil, fmt.Errorf("error creating request: %w", err)
+	}
+	for k, v := range n.auth {
+		if k == "" || v == "" {
+			continue
+		}
+		req.Header.Set(k, v)
+	}
+	return req, nil
+}
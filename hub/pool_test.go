@@ -0,0 +1,131 @@
+package hub
+
+import (
+	"context"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	qt "github.com/frankban/quicktest"
+	hubproto "github.com/vocdoni/farcaster-go/hub/proto"
+)
+
+// newTestPool function starts a stub hub for every given handler and returns
+// a Hub with a pool of them, in the same order.
+func newTestPool(t *testing.T, handlers ...http.HandlerFunc) *Hub {
+	endpoints := []*HubEndpoint{}
+	for _, handler := range handlers {
+		srv := httptest.NewServer(handler)
+		t.Cleanup(srv.Close)
+		endpoints = append(endpoints, &HubEndpoint{URL: srv.URL})
+	}
+	h, err := NewHubPool(endpoints, hubproto.FarcasterNetwork_FARCASTER_NETWORK_MAINNET)
+	qt.Assert(t, err, qt.IsNil)
+	return h
+}
+
+// infoHandler function returns a stub hub handler that serves the given hub
+// info and echoes the body of the rest of requests.
+func infoHandler(info *HubInfo) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/info" {
+			writeJSON(w, info)
+			return
+		}
+		_, _ = io.Copy(w, r.Body)
+	}
+}
+
+func TestPoolFailover(t *testing.T) {
+	c := qt.New(t)
+
+	unavailableRequests := atomic.Int32{}
+	unavailable := func(w http.ResponseWriter, _ *http.Request) {
+		unavailableRequests.Add(1)
+		w.WriteHeader(http.StatusServiceUnavailable)
+	}
+	healthy := &HubInfo{}
+	healthy.DBStats.NumMessages = 1000
+	h := newTestPool(t, unavailable, infoHandler(healthy))
+	c.Assert(h.nodesByPriority()[0].endpoint, qt.Equals, h.hubs[0].endpoint)
+
+	// before the health checks the unavailable hub is requested first, and
+	// the requests are retried in the next one
+	res, err := h.do(context.Background(), http.MethodGet, "castsByFid?fid=1", nil, "")
+	c.Assert(err, qt.IsNil)
+	c.Assert(res.StatusCode, qt.Equals, http.StatusOK)
+	res.Body.Close()
+	res, err = h.do(context.Background(), http.MethodPost, ENDPOINT_SUBMIT_MESSAGE, []byte("message"), "application/octet-stream")
+	c.Assert(err, qt.IsNil)
+	body, err := io.ReadAll(res.Body)
+	res.Body.Close()
+	c.Assert(err, qt.IsNil)
+	c.Assert(string(body), qt.Equals, "message")
+	c.Assert(unavailableRequests.Load(), qt.Equals, int32(2))
+
+	// after the health checks, the unavailable hub is the last resort
+	statuses := h.CheckHealth(context.Background())
+	c.Assert(statuses[0].Healthy, qt.IsFalse)
+	c.Assert(statuses[0].Error, qt.IsNotNil)
+	c.Assert(statuses[1].Healthy, qt.IsTrue)
+	c.Assert(h.nodesByPriority()[0].endpoint, qt.Equals, h.hubs[1].endpoint)
+	unavailableRequests.Store(0)
+	res, err = h.do(context.Background(), http.MethodGet, "castsByFid?fid=1", nil, "")
+	c.Assert(err, qt.IsNil)
+	res.Body.Close()
+	c.Assert(unavailableRequests.Load(), qt.Equals, int32(0))
+
+	// if every hub fails, the last error is returned
+	h = newTestPool(t, unavailable, unavailable)
+	_, err = h.do(context.Background(), http.MethodGet, "castsByFid?fid=1", nil, "")
+	c.Assert(err, qt.ErrorAs, new(*HubError))
+}
+
+func TestPoolCheckHealth(t *testing.T) {
+	c := qt.New(t)
+
+	infos := make([]*HubInfo, 4)
+	for i, messages := range []uint64{900, 1000, 995, 1000} {
+		infos[i] = &HubInfo{}
+		infos[i].DBStats.NumMessages = messages
+	}
+	infos[3].IsSyncing = true
+	h := newTestPool(t, infoHandler(infos[0]), infoHandler(infos[1]), infoHandler(infos[2]), infoHandler(infos[3]))
+
+	// the hubs behind the most synced one more than the allowed lag and the
+	// syncing ones are not healthy
+	statuses := h.CheckHealth(context.Background())
+	c.Assert(statuses, qt.HasLen, 4)
+	c.Assert(statuses[0].Healthy, qt.IsFalse)
+	c.Assert(statuses[1].Healthy, qt.IsTrue)
+	c.Assert(statuses[2].Healthy, qt.IsTrue)
+	c.Assert(statuses[3].Healthy, qt.IsFalse)
+	for _, status := range statuses {
+		c.Assert(status.Info, qt.IsNotNil)
+		c.Assert(status.LastCheck.IsZero(), qt.IsFalse)
+	}
+
+	// the healthy hubs go first, sorted by the number of messages
+	expected := []int{1, 2, 3, 0}
+	for i, node := range h.nodesByPriority() {
+		c.Assert(node.endpoint, qt.Equals, h.hubs[expected[i]].endpoint)
+	}
+
+	// the health checks run in background, even with an invalid interval
+	h = newTestPool(t, infoHandler(infos[1]))
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	h.StartHealthChecks(ctx, 0)
+	c.Assert(func() bool {
+		for i := 0; i < 100; i++ {
+			if !h.Status()[0].LastCheck.IsZero() {
+				return true
+			}
+			time.Sleep(10 * time.Millisecond)
+		}
+		return false
+	}(), qt.IsTrue)
+}
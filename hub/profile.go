@@ -0,0 +1,161 @@
+package hub
+
+import (
+	"context"
+	"fmt"
+	"regexp"
+	"strings"
+
+	hubproto "github.com/vocdoni/farcaster-go/hub/proto"
+	"go.vocdoni.io/dvote/log"
+)
+
+const (
+	// MaxPFPBytes is the maximum number of bytes of the profile picture URL.
+	MaxPFPBytes = 256
+	// MaxDisplayNameBytes is the maximum number of bytes of the display name.
+	MaxDisplayNameBytes = 32
+	// MaxBioBytes is the maximum number of bytes of the bio.
+	MaxBioBytes = 256
+	// MaxURLBytes is the maximum number of bytes of the profile URL.
+	MaxURLBytes = 256
+	// MaxENSNameBytes is the maximum number of bytes of an ENS username.
+	MaxENSNameBytes = 20
+
+	ensNameSuffix = ".eth"
+)
+
+// fnameRgx matches the valid fnames, it is also used to validate the name part
+// of the ENS usernames
+var fnameRgx = regexp.MustCompile(`^[a-z0-9][a-z0-9-]{0,15}$`)
+
+// SetDisplayName method sets the display name of the configured farcaster
+// user (with SetFarcasterUser).
+func (h *Hub) SetDisplayName(ctx context.Context, displayName string) error {
+	return h.SetUserData(ctx, hubproto.UserDataType_USER_DATA_TYPE_DISPLAY, displayName)
+}
+
+// SetBio method sets the bio of the configured farcaster user (with
+// SetFarcasterUser).
+func (h *Hub) SetBio(ctx context.Context, bio string) error {
+	return h.SetUserData(ctx, hubproto.UserDataType_USER_DATA_TYPE_BIO, bio)
+}
+
+// SetPFP method sets the profile picture URL of the configured farcaster user
+// (with SetFarcasterUser).
+func (h *Hub) SetPFP(ctx context.Context, pfpURL string) error {
+	return h.SetUserData(ctx, hubproto.UserDataType_USER_DATA_TYPE_PFP, pfpURL)
+}
+
+// SetURL method sets the profile URL of the configured farcaster user (with
+// SetFarcasterUser).
+func (h *Hub) SetURL(ctx context.Context, url string) error {
+	return h.SetUserData(ctx, hubproto.UserDataType_USER_DATA_TYPE_URL, url)
+}
+
+// SetUsername method sets the username of the configured farcaster user (with
+// SetFarcasterUser). The username must be an fname or an ENS name owned by
+// the user, or empty to remove the current one.
+func (h *Hub) SetUsername(ctx context.Context, username string) error {
+	return h.SetUserData(ctx, hubproto.UserDataType_USER_DATA_TYPE_USERNAME, username)
+}
+
+// SetUserData method sets the user data of the given type to the given value
+// for the configured farcaster user (with SetFarcasterUser). It validates the
+// value with the protocol limits before submitting it, returning an error that
+// matches ErrInvalidUserData if it is not valid.
+func (h *Hub) SetUserData(ctx context.Context, dataType hubproto.UserDataType, value string) error {
+	log.Infow("setting user data", "type", dataType.String(), "value", value)
+	if err := validateUserData(dataType, value); err != nil {
+		return err
+	}
+	msgBytes, err := h.buildAndSignMessage(&hubproto.MessageData{
+		Type: hubproto.MessageType_MESSAGE_TYPE_USER_DATA_ADD,
+		Body: &hubproto.MessageData_UserDataBody{
+			UserDataBody: &hubproto.UserDataBody{
+				Type:  dataType,
+				Value: value,
+			},
+		},
+	})
+	if err != nil {
+		return fmt.Errorf("error building and signing user data: %w", err)
+	}
+	return h.submitMessage(ctx, msgBytes)
+}
+
+// validateUserData function checks that the given value is valid for the
+// given user data type following the protocol rules. It returns an error that
+// matches ErrInvalidUserData if it is not valid.
+func validateUserData(dataType hubproto.UserDataType, value string) error {
+	switch dataType {
+	case hubproto.UserDataType_USER_DATA_TYPE_PFP:
+		if len(value) > MaxPFPBytes {
+			return fmt.Errorf("%w: pfp value > %d bytes", ErrInvalidUserData, MaxPFPBytes)
+		}
+	case hubproto.UserDataType_USER_DATA_TYPE_DISPLAY:
+		if len(value) > MaxDisplayNameBytes {
+			return fmt.Errorf("%w: display name value > %d bytes", ErrInvalidUserData, MaxDisplayNameBytes)
+		}
+	case hubproto.UserDataType_USER_DATA_TYPE_BIO:
+		if len(value) > MaxBioBytes {
+			return fmt.Errorf("%w: bio value > %d bytes", ErrInvalidUserData, MaxBioBytes)
+		}
+	case hubproto.UserDataType_USER_DATA_TYPE_URL:
+		if len(value) > MaxURLBytes {
+			return fmt.Errorf("%w: url value > %d bytes", ErrInvalidUserData, MaxURLBytes)
+		}
+	case hubproto.UserDataType_USER_DATA_TYPE_USERNAME:
+		// an empty username removes the current one
+		if value == "" {
+			return nil
+		}
+		if !strings.HasSuffix(value, ensNameSuffix) {
+			if !fnameRgx.MatchString(value) {
+				return fmt.Errorf("%w: invalid fname %s", ErrInvalidUserData, value)
+			}
+			return nil
+		}
+		if len(value) > MaxENSNameBytes {
+			return fmt.Errorf("%w: ens name value > %d bytes", ErrInvalidUserData, MaxENSNameBytes)
+		}
+		if !fnameRgx.MatchString(strings.TrimSuffix(value, ensNameSuffix)) {
+			return fmt.Errorf("%w: invalid ens name %s", ErrInvalidUserData, value)
+		}
+	default:
+		return fmt.Errorf("%w: unknown user data type %s", ErrInvalidUserData, dataType.String())
+	}
+	return nil
+}
+
+// applyUserdataMessage function applies the given user data add message to
+// the given profile, indexed by user data type, resolving the conflicts with
+// last-write-wins: the message with the highest timestamp prevails and, if
+// both have the same timestamp, the one with the highest hash.
+func applyUserdataMessage(profile map[string]*UserdataField, msg *hubMessage) {
+	if msg.Data.Type != MESSAGE_TYPE_USERDATA_ADD || msg.Data.UserDataBody == nil {
+		return
+	}
+	timestamp := msg.Data.Timestamp + farcasterEpoch
+	if current, ok := profile[msg.Data.UserDataBody.Type]; ok {
+		if timestamp < current.Timestamp ||
+			(timestamp == current.Timestamp && strings.ToLower(msg.HexHash) <= strings.ToLower(current.hash)) {
+			return
+		}
+	}
+	profile[msg.Data.UserDataBody.Type] = &UserdataField{
+		Value:     msg.Data.UserDataBody.Value,
+		Signer:    msg.Signer,
+		Timestamp: timestamp,
+		hash:      msg.HexHash,
+	}
+}
+
+// value method returns the value of the field or an empty string if it is
+// nil.
+func (f *UserdataField) value() string {
+	if f == nil {
+		return ""
+	}
+	return f.Value
+}
@@ -0,0 +1,221 @@
+package hub
+
+import (
+	"context"
+	"fmt"
+	"net/url"
+
+	hubproto "github.com/vocdoni/farcaster-go/hub/proto"
+	"go.vocdoni.io/dvote/log"
+)
+
+// Like method likes the given target (a cast or an URL) with the configured
+// farcaster user (with SetFarcasterUser).
+func (h *Hub) Like(ctx context.Context, target *ReactionTarget) error {
+	return h.react(ctx, hubproto.MessageType_MESSAGE_TYPE_REACTION_ADD, hubproto.ReactionType_REACTION_TYPE_LIKE, target)
+}
+
+// Unlike method removes the like of the configured farcaster user from the
+// given target (a cast or an URL).
+func (h *Hub) Unlike(ctx context.Context, target *ReactionTarget) error {
+	return h.react(ctx, hubproto.MessageType_MESSAGE_TYPE_REACTION_REMOVE, hubproto.ReactionType_REACTION_TYPE_LIKE, target)
+}
+
+// Recast method recasts the given target (a cast or an URL) with the
+// configured farcaster user (with SetFarcasterUser).
+func (h *Hub) Recast(ctx context.Context, target *ReactionTarget) error {
+	return h.react(ctx, hubproto.MessageType_MESSAGE_TYPE_REACTION_ADD, hubproto.ReactionType_REACTION_TYPE_RECAST, target)
+}
+
+// Unrecast method removes the recast of the configured farcaster user from
+// the given target (a cast or an URL).
+func (h *Hub) Unrecast(ctx context.Context, target *ReactionTarget) error {
+	return h.react(ctx, hubproto.MessageType_MESSAGE_TYPE_REACTION_REMOVE, hubproto.ReactionType_REACTION_TYPE_RECAST, target)
+}
+
+// ReactionsByCast method returns the reactions of the given type to the given
+// cast, following the given page options. If the reaction type is
+// REACTION_TYPE_NONE, every reaction is returned. It returns the reactions,
+// the token to request the next page (empty if there are no more pages) and
+// an error.
+func (h *Hub) ReactionsByCast(ctx context.Context, castID *CastID, reactionType hubproto.ReactionType,
+	opts *PageOptions,
+) ([]*Reaction, string, error) {
+	if castID == nil {
+		return nil, "", fmt.Errorf("no cast id provided")
+	}
+	uri := fmt.Sprintf(ENDPOINT_REACTIONS_BY_CAST, castID.FID, castID.Hash)
+	return h.reactions(ctx, uri, reactionType, opts)
+}
+
+// ReactionsByFID method returns the reactions of the given type made by the
+// user with the given fid, following the given page options. If the reaction
+// type is REACTION_TYPE_NONE, every reaction is returned. It returns the
+// reactions, the token to request the next page (empty if there are no more
+// pages) and an error.
+func (h *Hub) ReactionsByFID(ctx context.Context, fid uint64, reactionType hubproto.ReactionType,
+	opts *PageOptions,
+) ([]*Reaction, string, error) {
+	uri := fmt.Sprintf(ENDPOINT_REACTIONS_BY_FID, fid)
+	return h.reactions(ctx, uri, reactionType, opts)
+}
+
+// ReactionsByURL method returns the reactions of the given type to the given
+// URL, following the given page options. If the reaction type is
+// REACTION_TYPE_NONE, every reaction is returned. It returns the reactions,
+// the token to request the next page (empty if there are no more pages) and
+// an error.
+func (h *Hub) ReactionsByURL(ctx context.Context, targetURL string, reactionType hubproto.ReactionType,
+	opts *PageOptions,
+) ([]*Reaction, string, error) {
+	if targetURL == "" {
+		return nil, "", fmt.Errorf("no url provided")
+	}
+	uri := fmt.Sprintf(ENDPOINT_REACTIONS_BY_TARGET, url.QueryEscape(targetURL))
+	return h.reactions(ctx, uri, reactionType, opts)
+}
+
+// CastReactionCounts method returns the number of likes and recasts of the
+// given cast. It downloads every reaction to the cast to count them.
+func (h *Hub) CastReactionCounts(ctx context.Context, castID *CastID) (*ReactionCounts, error) {
+	reactions, _, err := h.ReactionsByCast(ctx, castID, hubproto.ReactionType_REACTION_TYPE_NONE, nil)
+	if err != nil {
+		return nil, err
+	}
+	counts := &ReactionCounts{}
+	for _, c := range CountReactions(reactions) {
+		counts.Likes += c.Likes
+		counts.Recasts += c.Recasts
+	}
+	return counts, nil
+}
+
+// CountReactions function aggregates the given reactions by the cast they
+// target. It returns a map with the number of likes and recasts indexed by
+// the hash of the casts. Every user is only counted once per cast and
+// reaction type, and the reactions to URLs are ignored.
+func CountReactions(reactions []*Reaction) map[string]*ReactionCounts {
+	counts := map[string]*ReactionCounts{}
+	seen := map[string]bool{}
+	for _, reaction := range reactions {
+		if reaction.Target == nil || reaction.Target.Cast == nil {
+			continue
+		}
+		hash := reaction.Target.Cast.Hash
+		key := fmt.Sprintf("%s/%d/%d", hash, reaction.FID, reaction.Type)
+		if seen[key] {
+			continue
+		}
+		seen[key] = true
+		if _, ok := counts[hash]; !ok {
+			counts[hash] = &ReactionCounts{}
+		}
+		switch reaction.Type {
+		case hubproto.ReactionType_REACTION_TYPE_LIKE:
+			counts[hash].Likes++
+		case hubproto.ReactionType_REACTION_TYPE_RECAST:
+			counts[hash].Recasts++
+		}
+	}
+	return counts
+}
+
+// reactions method lists the reactions returned by the given reactions
+// endpoint uri, filtered by the given reaction type (if it is not
+// REACTION_TYPE_NONE) and following the given page options.
+func (h *Hub) reactions(ctx context.Context, uri string, reactionType hubproto.ReactionType,
+	opts *PageOptions,
+) ([]*Reaction, string, error) {
+	if reactionType != hubproto.ReactionType_REACTION_TYPE_NONE {
+		uri += "&reaction_type=" + reactionType.String()
+	}
+	reactions := []*Reaction{}
+	nextPageToken, err := h.iterateMessages(ctx, uri, opts, func(msg *hubMessage) bool {
+		reaction, err := newReaction(msg)
+		if err != nil {
+			log.Warnw("invalid reaction", "hash", msg.HexHash, "error", err)
+			return true
+		}
+		// the hub could ignore the type filter, so check it again
+		if reactionType != hubproto.ReactionType_REACTION_TYPE_NONE && reaction.Type != reactionType {
+			return true
+		}
+		reactions = append(reactions, reaction)
+		return !opts.limitReached(len(reactions))
+	})
+	if err != nil {
+		return nil, "", fmt.Errorf("error downloading reactions: %w", err)
+	}
+	return reactions, nextPageToken, nil
+}
+
+// newReaction function creates a Reaction from the given hub reaction add
+// message. It returns an error if the message is not a valid reaction add.
+func newReaction(msg *hubMessage) (*Reaction, error) {
+	if msg.Data.Type != MESSAGE_TYPE_REACTION_ADD || msg.Data.ReactionBody == nil {
+		return nil, fmt.Errorf("no valid reaction")
+	}
+	body := msg.Data.ReactionBody
+	reactionType, ok := hubproto.ReactionType_value[body.Type]
+	if !ok {
+		return nil, fmt.Errorf("unknown reaction type: %s", body.Type)
+	}
+	target := &ReactionTarget{URL: body.TargetURL}
+	if body.TargetCastID != nil {
+		target.Cast = &CastID{FID: body.TargetCastID.FID, Hash: body.TargetCastID.Hash}
+	}
+	return &Reaction{
+		Type:      hubproto.ReactionType(reactionType),
+		FID:       msg.Data.From,
+		Hash:      msg.HexHash,
+		Timestamp: msg.Data.Timestamp + farcasterEpoch,
+		Target:    target,
+	}, nil
+}
+
+// react method builds, signs and submits a reaction message of the given
+// message type (add or remove) and reaction type (like or recast) to the given
+// target. It returns an error if the target is not valid or something goes
+// wrong submitting the message.
+func (h *Hub) react(ctx context.Context, msgType hubproto.MessageType,
+	reactionType hubproto.ReactionType, target *ReactionTarget,
+) error {
+	log.Infow("reacting to target", "type", msgType.String(), "reaction", reactionType.String(), "target", target)
+	reactionBody, err := newReactionBody(reactionType, target)
+	if err != nil {
+		return fmt.Errorf("error creating reaction body: %w", err)
+	}
+	msgBytes, err := h.buildAndSignMessage(&hubproto.MessageData{
+		Type: msgType,
+		Body: &hubproto.MessageData_ReactionBody{ReactionBody: reactionBody},
+	})
+	if err != nil {
+		return fmt.Errorf("error building and signing reaction: %w", err)
+	}
+	return h.submitMessage(ctx, msgBytes)
+}
+
+// newReactionBody function creates a new reaction body with the given
+// reaction type and target. The target must be a cast or an URL, but not
+// both. It returns an error if the target is not valid.
+func newReactionBody(reactionType hubproto.ReactionType, target *ReactionTarget) (*hubproto.ReactionBody, error) {
+	if target == nil || (target.Cast == nil && target.URL == "") || (target.Cast != nil && target.URL != "") {
+		return nil, fmt.Errorf("invalid reaction target")
+	}
+	reactionBody := &hubproto.ReactionBody{Type: reactionType}
+	if target.URL != "" {
+		reactionBody.Target = &hubproto.ReactionBody_TargetUrl{TargetUrl: target.URL}
+		return reactionBody, nil
+	}
+	hash, err := decodeHash(target.Cast.Hash)
+	if err != nil {
+		return nil, fmt.Errorf("error decoding target hash: %w", err)
+	}
+	reactionBody.Target = &hubproto.ReactionBody_TargetCastId{
+		TargetCastId: &hubproto.CastId{
+			Fid:  target.Cast.FID,
+			Hash: hash,
+		},
+	}
+	return reactionBody, nil
+}
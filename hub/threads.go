@@ -0,0 +1,117 @@
+package hub
+
+import (
+	"context"
+	"fmt"
+
+	"go.vocdoni.io/dvote/log"
+)
+
+// ThreadOptions struct defines the options to retrieve a thread of casts.
+type ThreadOptions struct {
+	// MaxDepth is the maximum depth of the replies to retrieve, the root cast
+	// is at depth 0 and its direct replies at depth 1. If it is 0, every
+	// level is retrieved.
+	MaxDepth int
+	// Limit is the maximum number of replies to include in the thread. The
+	// replies are retrieved level by level, so the closest ones to the root
+	// are included first. If it is 0, every reply is included.
+	Limit int
+}
+
+// Thread struct represents a cast of a thread with its depth from the root
+// cast and its replies, sorted as the hub returns them.
+type Thread struct {
+	Cast    *APIMessage
+	Depth   int
+	Replies []*Thread
+}
+
+// Thread method retrieves the whole conversation of the cast with the given
+// ID. It walks up the parents of the cast until the root cast (the first one
+// without a parent cast) and then fetches the replies recursively following
+// the given options (if nil, the whole thread is retrieved). It returns the
+// thread from the root cast and an error.
+func (h *Hub) Thread(ctx context.Context, castID *CastID, opts *ThreadOptions) (*Thread, error) {
+	if castID == nil {
+		return nil, fmt.Errorf("no cast id provided")
+	}
+	if opts == nil {
+		opts = &ThreadOptions{}
+	}
+	// walk up to the root cast, keeping track of the visited casts to avoid
+	// looping forever on malformed threads
+	visited := map[string]bool{}
+	root, err := h.castByID(ctx, castID.FID, castID.Hash)
+	if err != nil {
+		return nil, fmt.Errorf("error getting cast: %w", err)
+	}
+	visited[root.Hash] = true
+	for root.Parent != nil {
+		parent, err := h.castByID(ctx, root.Parent.FID, root.Parent.Hash)
+		if err != nil {
+			return nil, fmt.Errorf("error getting parent cast: %w", err)
+		}
+		if visited[parent.Hash] {
+			break
+		}
+		visited[parent.Hash] = true
+		root = parent
+	}
+	// fetch the replies level by level, starting from the root cast
+	thread := &Thread{Cast: root, Depth: 0, Replies: []*Thread{}}
+	replies := 0
+	level := []*Thread{thread}
+	for len(level) > 0 {
+		if opts.MaxDepth > 0 && level[0].Depth >= opts.MaxDepth {
+			break
+		}
+		nextLevel := []*Thread{}
+		for _, node := range level {
+			limit := 0
+			if opts.Limit > 0 {
+				if replies >= opts.Limit {
+					return thread, nil
+				}
+				limit = opts.Limit - replies
+			}
+			casts, err := h.castReplies(ctx, node.Cast, limit)
+			if err != nil {
+				return nil, err
+			}
+			for _, cast := range casts {
+				// skip the casts already included in the thread
+				if visited[cast.Hash] {
+					continue
+				}
+				visited[cast.Hash] = true
+				reply := &Thread{Cast: cast, Depth: node.Depth + 1, Replies: []*Thread{}}
+				node.Replies = append(node.Replies, reply)
+				nextLevel = append(nextLevel, reply)
+				replies++
+			}
+		}
+		level = nextLevel
+	}
+	return thread, nil
+}
+
+// castReplies method returns the direct replies of the given cast, up to the
+// given limit (if it is 0, every reply is returned).
+func (h *Hub) castReplies(ctx context.Context, cast *APIMessage, limit int) ([]*APIMessage, error) {
+	opts := &PageOptions{Limit: limit}
+	replies := []*APIMessage{}
+	uri := fmt.Sprintf(ENDPOINT_CASTS_BY_PARENT, cast.Author, cast.Hash)
+	if _, err := h.iterateMessages(ctx, uri, opts, func(msg *hubMessage) bool {
+		reply, err := h.apiMessage(ctx, msg)
+		if err != nil {
+			log.Warnw("discarding reply", "hash", msg.HexHash, "error", err)
+			return true
+		}
+		replies = append(replies, reply)
+		return !opts.limitReached(len(replies))
+	}); err != nil {
+		return nil, fmt.Errorf("error downloading replies: %w", err)
+	}
+	return replies, nil
+}
@@ -1,6 +1,11 @@
 package hub
 
-import "fmt"
+import (
+	"fmt"
+	"strings"
+
+	hubproto "github.com/vocdoni/farcaster-go/hub/proto"
+)
 
 // MaxCastBytes is the maximum number of bytes that a cast can have.
 const MaxCastBytes = 350
@@ -12,8 +17,91 @@ var (
 	ErrNoNewCasts = fmt.Errorf("no new casts")
 	// ErrChannelNotFound is returned when the requested channel is not found.
 	ErrChannelNotFound = fmt.Errorf("channel not found")
+	// ErrNotSupported is returned when the requested feature is not supported
+	// by the backend.
+	ErrNotSupported = fmt.Errorf("not supported")
+	// ErrCastNotOwned is returned when the cast to remove is not found between
+	// the casts of the configured user.
+	ErrCastNotOwned = fmt.Errorf("cast not found or not owned by the configured user")
+	// ErrInvalidMessage is matched by the hub errors returned when the hub
+	// rejects a message because it does not pass the validation.
+	ErrInvalidMessage = fmt.Errorf("invalid message")
+	// ErrDuplicateMessage is matched by the hub errors returned when the hub
+	// rejects a message because it already has it.
+	ErrDuplicateMessage = fmt.Errorf("duplicate message")
+	// ErrMessageConflict is matched by the hub errors returned when the hub
+	// rejects a message because it conflicts with a newer one.
+	ErrMessageConflict = fmt.Errorf("message conflict")
+	// ErrNotFound is matched by the hub errors returned when the hub does not
+	// find the requested resource.
+	ErrNotFound = fmt.Errorf("not found")
+	// ErrInvalidUserData is returned when the user data to set does not pass
+	// the protocol validation.
+	ErrInvalidUserData = fmt.Errorf("invalid user data")
+	// ErrInvalidHashScheme is returned when a message uses an unsupported hash
+	// scheme.
+	ErrInvalidHashScheme = fmt.Errorf("invalid hash scheme")
+	// ErrInvalidHash is returned when the hash of a message does not match its
+	// data.
+	ErrInvalidHash = fmt.Errorf("invalid message hash")
+	// ErrInvalidSignatureScheme is returned when a message uses an unsupported
+	// signature scheme.
+	ErrInvalidSignatureScheme = fmt.Errorf("invalid signature scheme")
+	// ErrInvalidSignature is returned when the signature of a message is not
+	// valid for its hash and signer.
+	ErrInvalidSignature = fmt.Errorf("invalid message signature")
+	// ErrNetworkMismatch is returned when a message is intended for a
+	// different farcaster network than the expected one.
+	ErrNetworkMismatch = fmt.Errorf("network mismatch")
+	// ErrInactiveSigner is returned when the signer of a message is not an
+	// active key of the message fid.
+	ErrInactiveSigner = fmt.Errorf("inactive signer")
 )
 
+// hub error codes prefixes used to match the hub errors with the generic
+// errors of this package
+const (
+	hubErrCodeValidation = "bad_request.validation_failure"
+	hubErrCodeInvalid    = "bad_request.invalid_param"
+	hubErrCodeDuplicate  = "bad_request.duplicate"
+	hubErrCodeConflict   = "bad_request.conflict"
+	hubErrCodeNotFound   = "not_found"
+)
+
+// HubError is returned when the hub rejects a request. It includes the HTTP
+// status code, the error code and the details provided by the hub. It can be
+// compared with ErrInvalidMessage, ErrDuplicateMessage, ErrMessageConflict and
+// ErrNotFound using errors.Is.
+type HubError struct {
+	StatusCode int
+	Code       string
+	Details    string
+}
+
+// Error method returns the string representation of the hub error.
+func (e *HubError) Error() string {
+	if e.Code == "" {
+		return fmt.Sprintf("hub error (%d): %s", e.StatusCode, e.Details)
+	}
+	return fmt.Sprintf("hub error (%d) %s: %s", e.StatusCode, e.Code, e.Details)
+}
+
+// Is method returns if the hub error matches the given generic error of this
+// package, based on the error code provided by the hub.
+func (e *HubError) Is(target error) bool {
+	switch target {
+	case ErrInvalidMessage:
+		return strings.HasPrefix(e.Code, hubErrCodeValidation) || strings.HasPrefix(e.Code, hubErrCodeInvalid)
+	case ErrDuplicateMessage:
+		return strings.HasPrefix(e.Code, hubErrCodeDuplicate)
+	case ErrMessageConflict:
+		return strings.HasPrefix(e.Code, hubErrCodeConflict)
+	case ErrNotFound:
+		return strings.HasPrefix(e.Code, hubErrCodeNotFound)
+	}
+	return false
+}
+
 // ParentAPIMessage is a struct that represents the parent message of an
 // APIMessage that does not includes the parent message itself, but only the
 // fid of the author and hash as reference of the parent message.
@@ -22,14 +110,80 @@ type ParentAPIMessage struct {
 	Hash string
 }
 
+// CastID is a struct that identifies a cast by the fid of its author and its
+// hexadecimal hash.
+type CastID struct {
+	FID  uint64
+	Hash string
+}
+
+// ReactionTarget is a struct that represents the target of a reaction, that
+// can be a cast or an URL. Only one of them must be set.
+type ReactionTarget struct {
+	Cast *CastID
+	URL  string
+}
+
+// Reaction struct represents a reaction (like or recast) of a user to a
+// target, a cast or an URL. The timestamp is a unix timestamp.
+type Reaction struct {
+	Type      hubproto.ReactionType
+	FID       uint64
+	Hash      string
+	Timestamp uint64
+	Target    *ReactionTarget
+}
+
+// ReactionCounts struct contains the number of likes and recasts of a cast.
+type ReactionCounts struct {
+	Likes   uint64
+	Recasts uint64
+}
+
+// PageOptions is a struct that represents the pagination options of the hub
+// list calls. By default, every page is fetched from the oldest to the newest
+// message.
+type PageOptions struct {
+	// PageSize is the number of messages requested to the hub per page. If it
+	// is 0, the hub default is used.
+	PageSize uint32
+	// PageToken is the token of the page to start from, returned by a previous
+	// call. If it is empty, the first page is requested.
+	PageToken string
+	// Reverse sorts the messages from the newest to the oldest.
+	Reverse bool
+	// Limit is the maximum number of results to return. If it is 0, every page
+	// is fetched.
+	Limit int
+}
+
+// limitReached method returns if the given number of results reaches the
+// limit of the page options.
+func (o *PageOptions) limitReached(n int) bool {
+	return o != nil && o.Limit > 0 && n >= o.Limit
+}
+
+// CastsOptions struct defines the options to list the casts of a user. The
+// timestamps are unix timestamps and, if they are 0, the range is not limited
+// on that side.
+type CastsOptions struct {
+	PageOptions
+	// StartTimestamp is the minimum timestamp of the casts to include.
+	StartTimestamp uint64
+	// EndTimestamp is the maximum timestamp of the casts to include.
+	EndTimestamp uint64
+}
+
 // APIMessage is a struct that represents a message in the farcaster API.
 type APIMessage struct {
-	IsMention bool
-	Content   string
-	Author    uint64
-	Hash      string
-	Parent    *ParentAPIMessage
-	Embeds    []string
+	IsMention  bool
+	Content    string
+	Author     uint64
+	Hash       string
+	Parent     *ParentAPIMessage
+	ParentURL  string
+	Embeds     []string
+	CastEmbeds []*CastID
 }
 
 // Userdata is a struct that represents the user data in the farcaster API.
@@ -39,9 +193,38 @@ type Userdata struct {
 	Displayname            string
 	CustodyAddress         string
 	VerificationsAddresses []string
+	SolanaAddresses        []string
+	Verifications          []*Verification
 	Signers                []string
 	Avatar                 string
 	Bio                    string
+	URL                    string
+	// Profile contains the latest value of every user data type of the user,
+	// indexed by the type name (for example, USER_DATA_TYPE_BIO), including
+	// the types not covered by the fields above. It is only filled by the
+	// hub API.
+	Profile map[string]*UserdataField
+}
+
+// Verification is a struct that represents an address verified by a user. The
+// Ethereum addresses are checksummed and the Solana addresses are base58
+// encoded. The timestamp is a unix timestamp.
+type Verification struct {
+	Address    string
+	Protocol   hubproto.Protocol
+	ChainID    uint32
+	IsContract bool
+	Signer     string
+	Timestamp  uint64
+}
+
+// UserdataField is a struct that represents the value of a user data type of
+// a user, with the signer and the unix timestamp of the message that set it.
+type UserdataField struct {
+	Value     string
+	Signer    string
+	Timestamp uint64
+	hash      string
 }
 
 // Channel is a struct that represents a channel in the farcaster API.
@@ -54,8 +237,14 @@ type Channel struct {
 	URL         string
 }
 
+type hubErrorResponse struct {
+	ErrCode string `json:"errCode"`
+	Details string `json:"details"`
+}
+
 type hubCastEmbeds struct {
-	Url string `json:"url"`
+	Url    string         `json:"url"`
+	CastID *hubParentCast `json:"castId"`
 }
 
 type hubParentCast struct {
@@ -72,70 +261,92 @@ type hubCastAddBody struct {
 	ParentCast        *hubParentCast   `json:"parentCastId"`
 }
 
-type hubMessageData struct {
-	Type        string          `json:"type"`
-	From        uint64          `json:"fid"`
-	Timestamp   uint64          `json:"timestamp"`
-	CastAddBody *hubCastAddBody `json:"castAddBody,omitempty"`
+type hubUserDataBody struct {
+	Type  string `json:"type"`
+	Value string `json:"value"`
 }
 
-type hubMessage struct {
-	Data    *hubMessageData `json:"data"`
-	HexHash string          `json:"hash"`
+type hubVerificationBody struct {
+	Address          string `json:"address"`
+	Protocol         string `json:"protocol"`
+	ChainID          uint32 `json:"chainId"`
+	VerificationType uint32 `json:"verificationType"`
 }
 
-type hubMessageResponse struct {
-	Messages []*hubMessage `json:"messages"`
+type hubLinkBody struct {
+	Type      string `json:"type"`
+	TargetFID uint64 `json:"targetFid"`
 }
 
-type usernameProofs struct {
-	Username       string `json:"name"`
-	CustodyAddress string `json:"owner"`
-	FID            uint64 `json:"fid"`
-	Type           string `json:"type"`
-	Timestamp      uint64 `json:"timestamp"`
+type hubReactionBody struct {
+	Type         string         `json:"type"`
+	TargetCastID *hubParentCast `json:"targetCastId,omitempty"`
+	TargetURL    string         `json:"targetUrl,omitempty"`
 }
 
-type custodyAddressResponse struct {
-	Proofs []*usernameProofs `json:"proofs"`
+type hubMessageData struct {
+	Type         string           `json:"type"`
+	From         uint64           `json:"fid"`
+	Timestamp    uint64           `json:"timestamp"`
+	Network      string           `json:"network"`
+	CastAddBody  *hubCastAddBody  `json:"castAddBody,omitempty"`
+	UserDataBody *hubUserDataBody `json:"userDataBody,omitempty"`
+	LinkBody     *hubLinkBody     `json:"linkBody,omitempty"`
+	ReactionBody *hubReactionBody `json:"reactionBody,omitempty"`
+	// the verification add body was renamed, older hubs still use the
+	// previous name
+	VerificationAddBody    *hubVerificationBody `json:"verificationAddAddressBody,omitempty"`
+	VerificationAddEthBody *hubVerificationBody `json:"verificationAddEthAddressBody,omitempty"`
+	VerificationRemoveBody *hubVerificationBody `json:"verificationRemoveBody,omitempty"`
 }
 
-type verification struct {
-	Address string `json:"address"`
+type hubMessage struct {
+	Data    *hubMessageData `json:"data"`
+	HexHash string          `json:"hash"`
+	Signer  string          `json:"signer"`
 }
 
-type verificationData struct {
-	Type         string        `json:"type"`
-	Verification *verification `json:"verificationAddEthAddressBody"`
-	Signer       string        `json:"signer"`
+type hubMessageResponse struct {
+	Messages      []*hubMessage `json:"messages"`
+	NextPageToken string        `json:"nextPageToken"`
 }
 
-type verificationMessage struct {
-	Data *verificationData `json:"data"`
+type hubEventBody struct {
+	Message         *hubMessage   `json:"message"`
+	DeletedMessages []*hubMessage `json:"deletedMessages"`
 }
 
-type verificationsResponse struct {
-	Messages []*verificationMessage `json:"messages"`
+type hubEvent struct {
+	Type              string        `json:"type"`
+	ID                uint64        `json:"id"`
+	MergeMessageBody  *hubEventBody `json:"mergeMessageBody,omitempty"`
+	PruneMessageBody  *hubEventBody `json:"pruneMessageBody,omitempty"`
+	RevokeMessageBody *hubEventBody `json:"revokeMessageBody,omitempty"`
 }
 
-type hubUserDataBody struct {
-	Type  string `json:"type"`
-	Value string `json:"value"`
+type hubEventsResponse struct {
+	NextPageEventID uint64      `json:"nextPageEventId"`
+	Events          []*hubEvent `json:"events"`
 }
 
-type hubUserData struct {
-	Type      string           `json:"type"`
-	FID       uint64           `json:"fid"`
-	Timestamp uint64           `json:"timestamp"`
-	Body      *hubUserDataBody `json:"userDataBody"`
+type hubValidateMessageResponse struct {
+	Valid bool `json:"valid"`
 }
 
-type hubUserDataMessage struct {
-	Data   *hubUserData `json:"data"`
-	Hash   string       `json:"hash"`
-	Signer string       `json:"signer"`
+type hubOnChainEventsResponse struct {
+	Events        []*OnChainEvent `json:"events"`
+	NextPageToken string          `json:"nextPageToken"`
 }
 
-type hubUserdataResponse struct {
-	Messages []*hubUserDataMessage `json:"messages"`
+type usernameProofs struct {
+	Username       string `json:"name"`
+	CustodyAddress string `json:"owner"`
+	Signature      string `json:"signature"`
+	FID            uint64 `json:"fid"`
+	Type           string `json:"type"`
+	Timestamp      uint64 `json:"timestamp"`
+}
+
+type custodyAddressResponse struct {
+	Proofs []*usernameProofs `json:"proofs"`
 }
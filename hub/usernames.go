@@ -0,0 +1,65 @@
+package hub
+
+import (
+	"context"
+	"encoding/base64"
+	"fmt"
+	"net/url"
+	"strings"
+
+	hubproto "github.com/vocdoni/farcaster-go/hub/proto"
+)
+
+// UserByUsername method returns the username proof of the given username
+// (fname or ENS name, with or without the leading @), that includes the fid of
+// the user, the type of the username, the owner address, the signature and
+// the timestamp of the proof. It returns an error that matches ErrNotFound if
+// the username is not registered.
+func (h *Hub) UserByUsername(ctx context.Context, name string) (*hubproto.UserNameProof, error) {
+	name = strings.ToLower(strings.TrimPrefix(strings.TrimSpace(name), "@"))
+	if name == "" {
+		return nil, fmt.Errorf("invalid username")
+	}
+	// create a intenal context with a timeout
+	internalCtx, cancel := context.WithTimeout(ctx, userdataTimeout)
+	defer cancel()
+	proof := &usernameProofs{}
+	if err := h.getJSON(internalCtx, fmt.Sprintf(ENDPOINT_USERNAME_PROOF, url.QueryEscape(name)), proof); err != nil {
+		return nil, fmt.Errorf("error downloading username proof: %w", err)
+	}
+	return proof.userNameProof()
+}
+
+// userNameProof method decodes the username proof returned by the hub into a
+// hubproto.UserNameProof.
+func (p *usernameProofs) userNameProof() (*hubproto.UserNameProof, error) {
+	nameType, ok := hubproto.UserNameType_value[p.Type]
+	if !ok {
+		return nil, fmt.Errorf("unknown username type: %s", p.Type)
+	}
+	owner, err := decodeHash(p.CustodyAddress)
+	if err != nil {
+		return nil, fmt.Errorf("error decoding owner: %w", err)
+	}
+	signature, err := decodeProofBytes(p.Signature)
+	if err != nil {
+		return nil, fmt.Errorf("error decoding signature: %w", err)
+	}
+	return &hubproto.UserNameProof{
+		Timestamp: p.Timestamp,
+		Name:      []byte(p.Username),
+		Owner:     owner,
+		Signature: signature,
+		Fid:       p.FID,
+		Type:      hubproto.UserNameType(nameType),
+	}, nil
+}
+
+// decodeProofBytes function decodes the given bytes field of a username
+// proof, that the hub encodes as hex with the 0x prefix or as base64.
+func decodeProofBytes(value string) ([]byte, error) {
+	if value == "" || strings.HasPrefix(value, "0x") {
+		return decodeHash(value)
+	}
+	return base64.StdEncoding.DecodeString(value)
+}
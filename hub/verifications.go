@@ -0,0 +1,129 @@
+package hub
+
+import (
+	"context"
+	"encoding/hex"
+	"fmt"
+	"strings"
+
+	"github.com/ethereum/go-ethereum/common"
+	hubproto "github.com/vocdoni/farcaster-go/hub/proto"
+	"go.vocdoni.io/dvote/log"
+)
+
+// verificationTypeContract is the verification type of the addresses of
+// smart contract wallets, the rest of them are EOAs.
+const verificationTypeContract = 1
+
+// Verifications method returns the current verified addresses of the user
+// with the given fid, of every protocol. The verification removes are applied
+// with last-write-wins per address, so the removed addresses are not
+// included.
+func (h *Hub) Verifications(ctx context.Context, fid uint64) ([]*Verification, error) {
+	type verificationState struct {
+		verification *Verification
+		removed      bool
+	}
+	order := []string{}
+	states := map[string]*verificationState{}
+	if _, err := h.iterateMessages(ctx, fmt.Sprintf(ENDPOINT_VERIFICATIONS, fid), nil, func(msg *hubMessage) bool {
+		verification, removed, err := newVerification(msg)
+		if err != nil {
+			log.Warnw("invalid verification message", "hash", msg.HexHash, "error", err)
+			return true
+		}
+		// the addresses are indexed by protocol and normalized address, the
+		// ethereum ones are checksummed and the solana ones are case sensitive
+		key := fmt.Sprintf("%d/%s", verification.Protocol, verification.Address)
+		state, ok := states[key]
+		if !ok {
+			order = append(order, key)
+			states[key] = &verificationState{verification: verification, removed: removed}
+			return true
+		}
+		current := state.verification.Timestamp
+		if verification.Timestamp > current || (verification.Timestamp == current && removed) {
+			state.verification = verification
+			state.removed = removed
+		}
+		return true
+	}); err != nil {
+		return nil, fmt.Errorf("error downloading verifications: %w", err)
+	}
+	verifications := []*Verification{}
+	for _, key := range order {
+		if !states[key].removed {
+			verifications = append(verifications, states[key].verification)
+		}
+	}
+	return verifications, nil
+}
+
+// newVerification function creates a Verification from the given hub
+// verification add or remove message. It returns the verification, if it is
+// a remove, and an error if the message is not a valid verification message.
+func newVerification(msg *hubMessage) (*Verification, bool, error) {
+	var body *hubVerificationBody
+	removed := false
+	switch msg.Data.Type {
+	case MESSAGE_TYPE_VERIFICATION:
+		body = msg.Data.VerificationAddBody
+		if body == nil {
+			body = msg.Data.VerificationAddEthBody
+		}
+	case MESSAGE_TYPE_VERIFICATION_REMOVE:
+		body = msg.Data.VerificationRemoveBody
+		removed = true
+	default:
+		return nil, false, fmt.Errorf("unexpected message type: %s", msg.Data.Type)
+	}
+	if body == nil {
+		return nil, false, fmt.Errorf("no verification body")
+	}
+	// the protocol could be omitted if it is the default one (ethereum)
+	protocol := hubproto.Protocol_PROTOCOL_ETHEREUM
+	if body.Protocol != "" {
+		value, ok := hubproto.Protocol_value[body.Protocol]
+		if !ok {
+			return nil, false, fmt.Errorf("unknown protocol: %s", body.Protocol)
+		}
+		protocol = hubproto.Protocol(value)
+	}
+	address, err := verificationAddress(protocol, body.Address)
+	if err != nil {
+		return nil, false, err
+	}
+	return &Verification{
+		Address:    address,
+		Protocol:   protocol,
+		ChainID:    body.ChainID,
+		IsContract: body.VerificationType == verificationTypeContract,
+		Signer:     msg.Signer,
+		Timestamp:  msg.Data.Timestamp + farcasterEpoch,
+	}, removed, nil
+}
+
+// verificationAddress function returns the given verified address in the
+// standard format of the given protocol: checksummed hex for Ethereum and
+// base58 for Solana. The hub encodes the Solana addresses as hex, but if the
+// address is not hex, it is returned as it is.
+func verificationAddress(protocol hubproto.Protocol, address string) (string, error) {
+	switch protocol {
+	case hubproto.Protocol_PROTOCOL_ETHEREUM:
+		if !common.IsHexAddress(address) {
+			return "", fmt.Errorf("invalid ethereum address: %s", address)
+		}
+		return common.HexToAddress(address).Hex(), nil
+	case hubproto.Protocol_PROTOCOL_SOLANA:
+		if !strings.HasPrefix(address, "0x") {
+			return address, nil
+		}
+		bAddress, err := hex.DecodeString(strings.TrimPrefix(address, "0x"))
+		if err != nil {
+			return "", fmt.Errorf("invalid solana address: %w", err)
+		}
+		return encodeBase58(bAddress), nil
+	default:
+		return "", fmt.Errorf("unsupported protocol: %s", protocol)
+	}
+}
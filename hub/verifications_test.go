@@ -0,0 +1,96 @@
+package hub
+
+import (
+	"context"
+	"net/http"
+	"testing"
+
+	qt "github.com/frankban/quicktest"
+	hubproto "github.com/vocdoni/farcaster-go/hub/proto"
+)
+
+const (
+	testEthAddress    = "0x5aAeb6053F3E94C9b9A09f33669435E7Ef1BeAed"
+	testSolanaAddress = "TokenkegQfeZyiNwAJbNbGKPFXCWuBvf9Ss623VQ5DA"
+	testSolanaHex     = "0x06ddf6e1d765a193d9cbe146ceeb79ac1cb485ed5f5b37913a8cf5857eff00a9"
+)
+
+func TestVerificationAddress(t *testing.T) {
+	tests := []struct {
+		protocol hubproto.Protocol
+		address  string
+		expected string
+		err      bool
+	}{
+		{hubproto.Protocol_PROTOCOL_ETHEREUM, "0x5aaeb6053f3e94c9b9a09f33669435e7ef1beaed", testEthAddress, false},
+		{hubproto.Protocol_PROTOCOL_ETHEREUM, testEthAddress, testEthAddress, false},
+		{hubproto.Protocol_PROTOCOL_ETHEREUM, "0x5aaeb6053f3e94c9b9a09f33669435e7ef1bea", "", true},
+		{hubproto.Protocol_PROTOCOL_SOLANA, testSolanaHex, testSolanaAddress, false},
+		{hubproto.Protocol_PROTOCOL_SOLANA, testSolanaAddress, testSolanaAddress, false},
+		{hubproto.Protocol_PROTOCOL_SOLANA, "0xzz", "", true},
+		{hubproto.Protocol(100), testEthAddress, "", true},
+	}
+	for _, test := range tests {
+		comment := qt.Commentf("protocol: %s, address: %s", test.protocol, test.address)
+		address, err := verificationAddress(test.protocol, test.address)
+		if test.err {
+			qt.Check(t, err, qt.IsNotNil, comment)
+			continue
+		}
+		qt.Check(t, err, qt.IsNil, comment)
+		qt.Check(t, address, qt.Equals, test.expected, comment)
+	}
+}
+
+func TestVerifications(t *testing.T) {
+	c := qt.New(t)
+
+	message := func(msgType, protocol, address string, timestamp uint64) *hubMessage {
+		body := &hubVerificationBody{Address: address, Protocol: protocol}
+		data := &hubMessageData{Type: msgType, Timestamp: timestamp}
+		if msgType == MESSAGE_TYPE_VERIFICATION_REMOVE {
+			data.VerificationRemoveBody = body
+		} else {
+			data.VerificationAddBody = body
+		}
+		return &hubMessage{Data: data, Signer: "0x01"}
+	}
+	const (
+		eth    = "PROTOCOL_ETHEREUM"
+		solana = "PROTOCOL_SOLANA"
+	)
+	messages := []*hubMessage{
+		// re-added after an older remove, in different case
+		message(MESSAGE_TYPE_VERIFICATION, "", "0x5aaeb6053f3e94c9b9a09f33669435e7ef1beaed", 10),
+		message(MESSAGE_TYPE_VERIFICATION_REMOVE, eth, testEthAddress, 5),
+		// removed later
+		message(MESSAGE_TYPE_VERIFICATION, eth, "0x0000000000000000000000000000000000000001", 10),
+		message(MESSAGE_TYPE_VERIFICATION_REMOVE, eth, "0x0000000000000000000000000000000000000001", 20),
+		// the remove wins the ties, in any order
+		message(MESSAGE_TYPE_VERIFICATION_REMOVE, solana, testSolanaHex, 10),
+		message(MESSAGE_TYPE_VERIFICATION, solana, testSolanaHex, 10),
+		// the solana addresses are case sensitive
+		message(MESSAGE_TYPE_VERIFICATION, solana, "So11111111111111111111111111111111111111112", 10),
+		message(MESSAGE_TYPE_VERIFICATION_REMOVE, solana, "so11111111111111111111111111111111111111112", 20),
+		// invalid messages are discarded
+		message(MESSAGE_TYPE_VERIFICATION, "PROTOCOL_UNKNOWN", testEthAddress, 10),
+		message(MESSAGE_TYPE_VERIFICATION_REMOVE, eth, testEthAddress+"00", 30),
+	}
+	h := newTestHub(t, func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/verificationsByFid" {
+			writeJSON(w, nil)
+			return
+		}
+		writeJSON(w, &hubMessageResponse{Messages: messages})
+	})
+
+	verifications, err := h.Verifications(context.Background(), 1)
+	c.Assert(err, qt.IsNil)
+	c.Assert(verifications, qt.HasLen, 2)
+	c.Assert(verifications[0].Address, qt.Equals, testEthAddress)
+	c.Assert(verifications[0].Protocol, qt.Equals, hubproto.Protocol_PROTOCOL_ETHEREUM)
+	c.Assert(verifications[0].Timestamp, qt.Equals, 10+farcasterEpoch)
+	c.Assert(verifications[0].Signer, qt.Equals, "0x01")
+	c.Assert(verifications[1].Address, qt.Equals, "So11111111111111111111111111111111111111112")
+	c.Assert(verifications[1].Protocol, qt.Equals, hubproto.Protocol_PROTOCOL_SOLANA)
+}
@@ -15,7 +15,6 @@ import (
 	"sync"
 	"time"
 
-	"github.com/ethereum/go-ethereum/common"
 	"github.com/vocdoni/farcaster-go/hub"
 	"go.vocdoni.io/dvote/log"
 	"go.vocdoni.io/dvote/util"
@@ -70,6 +69,8 @@ type NeynarAPI struct {
 	newCastsMtx  sync.Mutex
 }
 
+var _ hub.API = (*NeynarAPI)(nil)
+
 // NewNeynarAPI creates a new NeynarAPI client with the given API key.
 func NewNeynarAPI(apiKey string) (*NeynarAPI, error) {
 	if apiKey == "" {
@@ -154,7 +155,7 @@ func (n *NeynarAPI) Publish(ctx context.Context, content string, _ []uint64, emb
 	castEmbeds := []*castEmbed{}
 	if len(embeds) > 0 {
 		for _, embed := range embeds {
-			castEmbeds = append(castEmbeds, &castEmbed{embed})
+			castEmbeds = append(castEmbeds, &castEmbed{Url: embed})
 		}
 	}
 	// create request body
@@ -185,7 +186,7 @@ func (n *NeynarAPI) Reply(ctx context.Context, targetMsg *hub.APIMessage,
 	castEmbeds := []*castEmbed{}
 	if len(embeds) > 0 {
 		for _, embed := range embeds {
-			castEmbeds = append(castEmbeds, &castEmbed{embed})
+			castEmbeds = append(castEmbeds, &castEmbed{Url: embed})
 		}
 	}
 	// create request body
@@ -204,9 +205,9 @@ func (n *NeynarAPI) Reply(ctx context.Context, targetMsg *hub.APIMessage,
 	return err
 }
 
-// UserData method returns the username, the custody address and the
+// UserDataByFID method returns the username, the custody address and the
 // verification addresses of the user with the given fid.
-func (n *NeynarAPI) UserDataByFID(ctx context.Context, fid uint64) (*UserdataV2, error) {
+func (n *NeynarAPI) UserDataByFID(ctx context.Context, fid uint64) (*hub.Userdata, error) {
 	// create request with the bot fid
 	url := fmt.Sprintf(neynarGetUsernameEndpoint, fid)
 	body, err := n.request(ctx, url, http.MethodGet, nil, defaultRequestTimeout)
@@ -221,11 +222,11 @@ func (n *NeynarAPI) UserDataByFID(ctx context.Context, fid uint64) (*UserdataV2,
 	if len(usernameResponse.Users) == 0 {
 		return nil, hub.ErrNoDataFound
 	}
-	return usernameResponse.Users[0], nil
+	return usernameResponse.Users[0].userdata(), nil
 }
 
 // UserDataByVerificationAddresses returns the list of users that hold at least one of the given addresses.
-func (n *NeynarAPI) UserDataByVerificationAddresses(ctx context.Context, addresses []string) ([]*UserdataV2, error) {
+func (n *NeynarAPI) UserDataByVerificationAddresses(ctx context.Context, addresses []string) ([]*hub.Userdata, error) {
 	if len(addresses) > MaxAddressesPerRequest {
 		return nil, fmt.Errorf("address slice exceeds the maximum limit of 350 addresses")
 	}
@@ -243,21 +244,16 @@ func (n *NeynarAPI) UserDataByVerificationAddresses(ctx context.Context, address
 	if err := json.Unmarshal(body, &results); err != nil {
 		return nil, fmt.Errorf("error unmarshalling response body: %w", err)
 	}
-	// Process results into []*farcasterapi.Userdata
-	userDataSlice := []*UserdataV2{}
+	// Process results into []*hub.Userdata
+	userDataSlice := []*hub.Userdata{}
 	for _, dataItems := range results {
 		for _, item := range dataItems {
 			if item.Username != "" {
-				if len(item.VerifiedAddresses.EthAddresses) == 0 {
+				if item.VerifiedAddresses == nil || len(item.VerifiedAddresses.EthAddresses) == 0 {
 					log.Warnw("no verified addresses found", "user", item.Username)
 					continue
 				}
-				// Normalize addresses to the Ethereum hex standard format
-				var normalizedAddresses []string
-				for _, addr := range item.VerifiedAddresses.EthAddresses {
-					normalizedAddresses = append(normalizedAddresses, common.HexToAddress(addr).Hex())
-				}
-				userDataSlice = append(userDataSlice, &item)
+				userDataSlice = append(userDataSlice, item.userdata())
 				break // we only need the first valid user data per address
 			}
 		}
@@ -531,6 +527,7 @@ func (n *NeynarAPI) parseCastData(data *castWebhookData) (*hub.APIMessage, error
 		Author:    data.Author.Fid,
 		Content:   data.Text,
 		Hash:      data.Hash,
+		ParentURL: data.ParentURL,
 	}
 	// include the parent parent cast info if it exists
 	if data.ParentAuthor != nil {
@@ -542,7 +539,15 @@ func (n *NeynarAPI) parseCastData(data *castWebhookData) (*hub.APIMessage, error
 	// parse the embeds and include them in the message
 	if len(data.Embeds) > 0 {
 		message.Embeds = []string{}
+		message.CastEmbeds = []*hub.CastID{}
 		for _, embed := range data.Embeds {
+			if embed.CastID != nil {
+				message.CastEmbeds = append(message.CastEmbeds, &hub.CastID{
+					FID:  embed.CastID.FID,
+					Hash: embed.CastID.Hash,
+				})
+				continue
+			}
 			message.Embeds = append(message.Embeds, embed.Url)
 		}
 	}
@@ -1,7 +1,18 @@
 package neynar
 
+import (
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/vocdoni/farcaster-go/hub"
+)
+
+type castEmbedID struct {
+	FID  uint64 `json:"fid"`
+	Hash string `json:"hash"`
+}
+
 type castEmbed struct {
-	Url string `json:"url"`
+	Url    string       `json:"url,omitempty"`
+	CastID *castEmbedID `json:"cast_id,omitempty"`
 }
 
 type castPostRequest struct {
@@ -55,6 +66,31 @@ type UserdataV2 struct {
 	ActiveStatus      string               `json:"active_status"`
 }
 
+// userdata method converts the Neynar user data to the hub.Userdata shared
+// by every backend. The verification addresses are normalized to the Ethereum
+// hex standard format.
+func (u *UserdataV2) userdata() *hub.Userdata {
+	verifications := []string{}
+	solanaAddresses := []string{}
+	if u.VerifiedAddresses != nil {
+		for _, addr := range u.VerifiedAddresses.EthAddresses {
+			verifications = append(verifications, common.HexToAddress(addr).Hex())
+		}
+		solanaAddresses = append(solanaAddresses, u.VerifiedAddresses.SolAddresses...)
+	}
+	return &hub.Userdata{
+		FID:                    u.Fid,
+		Username:               u.Username,
+		Displayname:            u.DisplayName,
+		CustodyAddress:         u.CustodyAddress,
+		VerificationsAddresses: verifications,
+		SolanaAddresses:        solanaAddresses,
+		Signers:                []string{},
+		Avatar:                 u.PfpUrl,
+		Bio:                    u.Profile.Bio.Text,
+	}
+}
+
 type cursor struct {
 	Cursor string `json:"cursor"`
 }